@@ -0,0 +1,220 @@
+package cloudmailin
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AddressFormat is the format used to POST email received by an Address to
+// its target.
+type AddressFormat string
+
+const (
+	// AddressFormatJSON posts the normalized JSON format parsed by
+	// ParseIncoming.
+	AddressFormatJSON AddressFormat = "json"
+
+	// AddressFormatMultipart posts the normalized format as
+	// multipart/form-data.
+	AddressFormatMultipart AddressFormat = "multipart"
+
+	// AddressFormatRaw posts the envelope with the full original message.
+	AddressFormatRaw AddressFormat = "raw"
+)
+
+// Address is an inbound email address and the target URL that received
+// email is delivered to.
+type Address struct {
+	ID        string        `json:"id"`
+	Address   string        `json:"address"`
+	Target    string        `json:"target"`
+	Format    AddressFormat `json:"format"`
+	Auth      AddressAuth   `json:"auth"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// AddressAuth contains the basic authentication credentials CloudMailin uses
+// when posting to the target. The password is not returned by the API.
+type AddressAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// AddressParams contains the settings to create or update an Address. Empty
+// fields are left unchanged when updating.
+type AddressParams struct {
+	Address string        `json:"address,omitempty"`
+	Target  string        `json:"target,omitempty"`
+	Format  AddressFormat `json:"format,omitempty"`
+	Auth    *AddressAuth  `json:"auth,omitempty"`
+}
+
+// Domain is an outbound sending domain and its verification status.
+type Domain struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Verified bool   `json:"verified"`
+
+	// The status of each verification check such as SPF or DKIM.
+	Verification map[string]string `json:"verification"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListAddresses returns every inbound address for the account, fetching
+// all pages. Use Addresses to iterate through large accounts.
+func (client Client) ListAddresses(ctx context.Context) (addresses []Address, err error) {
+	it := client.Addresses(ctx, ListOptions{})
+	for it.Next() {
+		addresses = append(addresses, it.Address())
+	}
+	return addresses, it.Err()
+}
+
+// Addresses returns an AddressIterator for the inbound addresses of the
+// account.
+func (client Client) Addresses(ctx context.Context, opts ListOptions) *AddressIterator {
+	return &AddressIterator{pager: client.newListPager(ctx, "/addresses", nil, opts,
+		RequestTypeAccount)}
+}
+
+// GetAddress returns a single inbound address by its ID.
+func (client Client) GetAddress(ctx context.Context, id string) (address Address,
+	res *http.Response, err error) {
+
+	res, err = client.account(ctx, "GET", "/addresses/"+url.PathEscape(id), nil, &address)
+	return
+}
+
+// CreateAddress creates a new inbound address.
+func (client Client) CreateAddress(ctx context.Context, params AddressParams) (
+	address Address, res *http.Response, err error) {
+
+	res, err = client.account(ctx, "POST", "/addresses", params, &address)
+	return
+}
+
+// UpdateAddress changes the settings of an inbound address.
+func (client Client) UpdateAddress(ctx context.Context, id string,
+	params AddressParams) (address Address, res *http.Response, err error) {
+
+	res, err = client.account(ctx, "PATCH", "/addresses/"+url.PathEscape(id), params, &address)
+	return
+}
+
+// DeleteAddress removes an inbound address.
+func (client Client) DeleteAddress(ctx context.Context, id string) (
+	res *http.Response, err error) {
+
+	res, err = client.account(ctx, "DELETE", "/addresses/"+url.PathEscape(id), nil, nil)
+	if err == nil {
+		res.Body.Close()
+	}
+	return
+}
+
+// ListDomains returns every outbound sending domain for the account,
+// fetching all pages.
+func (client Client) ListDomains(ctx context.Context) (domains []Domain, err error) {
+	it := client.Domains(ctx, ListOptions{})
+	for it.Next() {
+		domains = append(domains, it.Domain())
+	}
+	return domains, it.Err()
+}
+
+// Domains returns a DomainIterator for the outbound sending domains of the
+// account.
+func (client Client) Domains(ctx context.Context, opts ListOptions) *DomainIterator {
+	return &DomainIterator{pager: client.newListPager(ctx, "/domains", nil, opts,
+		RequestTypeAccount)}
+}
+
+// account performs an account API request decoding the response into out.
+func (client Client) account(ctx context.Context, method string, path string,
+	body interface{}, out interface{}) (*http.Response, error) {
+
+	req, err := client.NewRequest(ctx, method, path, nil, body, RequestTypeAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.DoRequest(req, out)
+}
+
+// AddressIterator walks through the pages of inbound addresses, see
+// MessageIterator for usage.
+type AddressIterator struct {
+	pager   *Pager
+	page    []Address
+	current Address
+}
+
+// Next advances to the next address, returning false at the end of the
+// addresses or if an error occurred.
+func (it *AddressIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.pager.NextPage(&it.page) {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Address returns the current address.
+func (it *AddressIterator) Address() Address {
+	return it.current
+}
+
+// Stop ends the iteration early without fetching any further pages.
+func (it *AddressIterator) Stop() {
+	it.pager.Stop()
+	it.page = nil
+}
+
+// Err returns the first error that occurred while fetching pages.
+func (it *AddressIterator) Err() error {
+	return it.pager.Err()
+}
+
+// DomainIterator walks through the pages of sending domains, see
+// MessageIterator for usage.
+type DomainIterator struct {
+	pager   *Pager
+	page    []Domain
+	current Domain
+}
+
+// Next advances to the next domain, returning false at the end of the
+// domains or if an error occurred.
+func (it *DomainIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.pager.NextPage(&it.page) {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Domain returns the current domain.
+func (it *DomainIterator) Domain() Domain {
+	return it.current
+}
+
+// Stop ends the iteration early without fetching any further pages.
+func (it *DomainIterator) Stop() {
+	it.pager.Stop()
+	it.page = nil
+}
+
+// Err returns the first error that occurred while fetching pages.
+func (it *DomainIterator) Err() error {
+	return it.pager.Err()
+}
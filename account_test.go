@@ -0,0 +1,169 @@
+package cloudmailin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeAccountAPI is a minimal in-memory implementation of the account API.
+func fakeAccountAPI(t *testing.T) *httptest.Server {
+	addresses := map[string]Address{
+		"1": {ID: "1", Address: "a@cloudmailin.net", Target: "https://example.com/1",
+			Format: AddressFormatJSON},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/account")
+		id := strings.TrimPrefix(path, "/addresses/")
+		switch {
+		case r.Method == "GET" && path == "/addresses":
+			list := []Address{}
+			for _, address := range addresses {
+				list = append(list, address)
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == "POST" && path == "/addresses":
+			var params AddressParams
+			json.NewDecoder(r.Body).Decode(&params)
+			address := Address{ID: "2", Address: params.Address, Target: params.Target,
+				Format: params.Format}
+			addresses[address.ID] = address
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(address)
+		case path == "/domains":
+			w.Write([]byte(`[{"id":"d1","name":"example.com","verified":true,` +
+				`"verification":{"dkim":"verified","spf":"pending"}}]`))
+		case addresses[id].ID == "":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "GET":
+			json.NewEncoder(w).Encode(addresses[id])
+		case r.Method == "PATCH":
+			var params AddressParams
+			json.NewDecoder(r.Body).Decode(&params)
+			address := addresses[id]
+			if params.Target != "" {
+				address.Target = params.Target
+			}
+			if params.Auth != nil {
+				address.Auth.Username = params.Auth.Username
+			}
+			addresses[id] = address
+			json.NewEncoder(w).Encode(address)
+		case r.Method == "DELETE":
+			delete(addresses, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestClient_Addresses(t *testing.T) {
+	server := fakeAccountAPI(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+
+	t.Run("List", func(t *testing.T) {
+		addresses, err := client.ListAddresses(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(addresses) != 1 || addresses[0].Target != "https://example.com/1" {
+			t.Errorf("Unexpected addresses {%v}", addresses)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		address, res, err := client.CreateAddress(ctx, AddressParams{
+			Address: "b@cloudmailin.net",
+			Target:  "https://example.com/2",
+			Format:  AddressFormatMultipart,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := Address{ID: "2", Address: "b@cloudmailin.net",
+			Target: "https://example.com/2", Format: AddressFormatMultipart}
+		if !cmp.Equal(expected, address) || res.StatusCode != http.StatusCreated {
+			t.Errorf("Unexpected address {%v}", cmp.Diff(expected, address))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		address, _, err := client.UpdateAddress(ctx, "2", AddressParams{
+			Auth: &AddressAuth{Username: "user", Password: "secret"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if address.Auth.Username != "user" || address.Target != "https://example.com/2" {
+			t.Errorf("Unexpected address {%v}", address)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if _, err := client.DeleteAddress(ctx, "2"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, res, err := client.GetAddress(ctx, "2")
+		if res == nil || res.StatusCode != http.StatusNotFound || err == nil {
+			t.Errorf("Expected 404 got {%v}", err)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		client := client
+		client.AccountToken = "wrong"
+		_, err := client.ListAddresses(ctx)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized got {%v}", err)
+		}
+	})
+
+	t.Run("Missing credentials", func(t *testing.T) {
+		client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+		_, err := client.ListAddresses(ctx)
+		if !errors.Is(err, ErrMissingCredentials) {
+			t.Errorf("Expected ErrMissingCredentials got {%v}", err)
+		}
+	})
+}
+
+func TestClient_ListDomains(t *testing.T) {
+	server := fakeAccountAPI(t)
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+	domains, err := client.ListDomains(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Domain{{
+		ID:           "d1",
+		Name:         "example.com",
+		Verified:     true,
+		Verification: map[string]string{"dkim": "verified", "spf": "pending"},
+	}}
+	if !cmp.Equal(expected, domains) {
+		t.Errorf("Unexpected domains {%v}", cmp.Diff(expected, domains))
+	}
+}
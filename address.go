@@ -0,0 +1,202 @@
+package cloudmailin
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var addressParser = &mail.AddressParser{WordDecoder: headerDecoder}
+
+// FromAddress parses the From header.
+func (i IncomingMailHeaders) FromAddress() (*mail.Address, error) {
+	addresses, err := i.addresses("from")
+	if err != nil || len(addresses) == 0 {
+		return nil, err
+	}
+	return addresses[0], nil
+}
+
+// ToAddresses parses the To header.
+func (i IncomingMailHeaders) ToAddresses() ([]*mail.Address, error) {
+	return i.addresses("to")
+}
+
+// CCAddresses parses the Cc header.
+func (i IncomingMailHeaders) CCAddresses() ([]*mail.Address, error) {
+	return i.addresses("cc")
+}
+
+// ReplyToAddresses parses the Reply-To header.
+func (i IncomingMailHeaders) ReplyToAddresses() ([]*mail.Address, error) {
+	return i.addresses("reply_to")
+}
+
+// addresses parses the address list in the header key. Encoded-words in
+// display names are decoded and if the header is not valid RFC 5322 each
+// address is extracted as well as possible.
+func (i IncomingMailHeaders) addresses(key string) ([]*mail.Address, error) {
+	value := strings.TrimSpace(i.First(key))
+	if value == "" {
+		return nil, nil
+	}
+
+	if addresses, err := addressParser.ParseList(value); err == nil {
+		return addresses, nil
+	}
+
+	return parseAddressesLeniently(value)
+}
+
+var (
+	angleAddressPattern = regexp.MustCompile(`<\s*([^<>\s]+@[^<>\s]+)\s*>`)
+	bareAddressPattern  = regexp.MustCompile(`[^\s<>"(),;:]+@[^\s<>"(),;:]+`)
+)
+
+// parseAddressesLeniently extracts addresses from a malformed header such
+// as one using semicolons, unquoted special characters in display names or
+// missing angle brackets.
+func parseAddressesLeniently(value string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+
+	for _, item := range splitAddressList(value) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if address, err := addressParser.Parse(item); err == nil {
+			addresses = append(addresses, address)
+			continue
+		}
+
+		address := &mail.Address{}
+		if match := angleAddressPattern.FindStringSubmatchIndex(item); match != nil {
+			address.Address = item[match[2]:match[3]]
+			address.Name = item[:match[0]]
+		} else if match := bareAddressPattern.FindStringIndex(item); match != nil {
+			address.Address = item[match[0]:match[1]]
+			address.Name = item[:match[0]] + item[match[1]:]
+		} else {
+			continue
+		}
+
+		address.Name = strings.Trim(strings.TrimSpace(address.Name), `"'()`)
+		address.Name = decodeHeader(strings.Replace(address.Name, `\"`, `"`, -1))
+		addresses = append(addresses, address)
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses found in %q", value)
+	}
+	return addresses, nil
+}
+
+// splitAddressList splits an address list on commas and semicolons that
+// are not inside quotes, comments or angle brackets.
+func splitAddressList(value string) []string {
+	var items []string
+	var quoted, escaped bool
+	depth, start := 0, 0
+
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '<' || r == '(':
+			depth++
+		case (r == '>' || r == ')') && depth > 0:
+			depth--
+		case (r == ',' || r == ';') && depth == 0:
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(items, value[start:])
+}
+
+// EnvelopeAddress is an SMTP envelope address split into its parts. For
+// reply+1234@example.com the LocalPart is reply+1234, the User is reply,
+// the Tag is 1234 and the Domain is example.com.
+type EnvelopeAddress struct {
+	Address   string
+	LocalPart string
+	User      string
+	Tag       string
+	Domain    string
+}
+
+// ParseEnvelopeAddress splits an envelope address into its parts. Angle
+// brackets are removed and the Domain is converted to lower case.
+func ParseEnvelopeAddress(address string) (EnvelopeAddress, error) {
+	address = strings.TrimSpace(address)
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "<"), ">")
+
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return EnvelopeAddress{}, fmt.Errorf("invalid envelope address %q", address)
+	}
+
+	parsed := EnvelopeAddress{
+		Address:   address,
+		LocalPart: address[:at],
+		User:      address[:at],
+		Domain:    strings.ToLower(address[at+1:]),
+	}
+
+	if plus := strings.Index(parsed.LocalPart, "+"); plus >= 0 {
+		parsed.User = parsed.LocalPart[:plus]
+		parsed.Tag = parsed.LocalPart[plus+1:]
+	}
+
+	return parsed, nil
+}
+
+// Base returns the address without the tag, such as reply@example.com.
+func (a EnvelopeAddress) Base() string {
+	return a.User + "@" + a.Domain
+}
+
+// String returns the original address.
+func (a EnvelopeAddress) String() string {
+	return a.Address
+}
+
+// ErrNullSender is returned by Sender for bounces and other messages sent
+// with an empty envelope sender.
+var ErrNullSender = errors.New("envelope has a null sender")
+
+// Sender parses the envelope From address.
+func (e IncomingMailEnvelope) Sender() (EnvelopeAddress, error) {
+	if from := strings.TrimSpace(e.From); from == "" || from == "<>" {
+		return EnvelopeAddress{}, ErrNullSender
+	}
+	return ParseEnvelopeAddress(e.From)
+}
+
+// Recipient parses the envelope To address, the address that the email
+// was delivered to.
+func (e IncomingMailEnvelope) Recipient() (EnvelopeAddress, error) {
+	return ParseEnvelopeAddress(e.To)
+}
+
+// RecipientAddresses parses each of the envelope Recipients.
+func (e IncomingMailEnvelope) RecipientAddresses() ([]EnvelopeAddress, error) {
+	addresses := make([]EnvelopeAddress, 0, len(e.Recipients))
+	for _, recipient := range e.Recipients {
+		address, err := ParseEnvelopeAddress(recipient)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,169 @@
+package cloudmailin
+
+import (
+	"errors"
+	"net/mail"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncomingMailHeaders_FromAddress(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	from, err := message.Headers.FromAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &mail.Address{Name: "Steve Smith", Address: "test@example.com"}
+	if !cmp.Equal(expected, from) {
+		t.Errorf("Unexpected address {%v}", cmp.Diff(expected, from))
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		from, err := IncomingMailHeaders{}.FromAddress()
+		if from != nil || err != nil {
+			t.Errorf("Expected nil got {%v}, {%v}", from, err)
+		}
+	})
+}
+
+func TestIncomingMailHeaders_Addresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []*mail.Address
+	}{
+		{"Valid list", `"Smith, Steve" <steve@example.com>, jane@example.com`, []*mail.Address{
+			{Name: "Smith, Steve", Address: "steve@example.com"},
+			{Address: "jane@example.com"},
+		}},
+		{"Encoded word", `=?UTF-8?Q?Andr=C3=A9?= <andre@example.com>`, []*mail.Address{
+			{Name: "André", Address: "andre@example.com"},
+		}},
+		{"Semicolons", `steve@example.com; Jane <jane@example.com>`, []*mail.Address{
+			{Address: "steve@example.com"},
+			{Name: "Jane", Address: "jane@example.com"},
+		}},
+		{"Unquoted specials", `Steve Smith [Example] <steve@example.com>, Dr. J. Doe <jd@example.com>`, []*mail.Address{
+			{Name: "Steve Smith [Example]", Address: "steve@example.com"},
+			{Name: "Dr. J. Doe", Address: "jd@example.com"},
+		}},
+		{"Trailing comma", `steve@example.com,`, []*mail.Address{
+			{Address: "steve@example.com"},
+		}},
+		{"Name after address", `steve@example.com (Steve Smith)`, []*mail.Address{
+			{Name: "Steve Smith", Address: "steve@example.com"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := IncomingMailHeaders{"to": {tt.header}, "cc": {tt.header},
+				"reply_to": {tt.header}}
+
+			for _, fn := range []func() ([]*mail.Address, error){
+				headers.ToAddresses, headers.CCAddresses, headers.ReplyToAddresses,
+			} {
+				addresses, err := fn()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !cmp.Equal(tt.expected, addresses) {
+					t.Errorf("Unexpected addresses {%v}", cmp.Diff(tt.expected, addresses))
+				}
+			}
+		})
+	}
+
+	t.Run("No addresses", func(t *testing.T) {
+		_, err := IncomingMailHeaders{"to": {"undisclosed-recipients:;"}}.ToAddresses()
+		if err != nil {
+			t.Errorf("Expected empty group to parse got {%v}", err)
+		}
+
+		_, err = IncomingMailHeaders{"to": {"nobody here"}}.ToAddresses()
+		if err == nil {
+			t.Error("Expected error but was nil")
+		}
+	})
+}
+
+func TestParseEnvelopeAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected EnvelopeAddress
+	}{
+		{"from+test@cloudmailin.net", EnvelopeAddress{
+			Address: "from+test@cloudmailin.net", LocalPart: "from+test", User: "from",
+			Tag: "test", Domain: "cloudmailin.net",
+		}},
+		{"<Postman@CloudMailin.NET>", EnvelopeAddress{
+			Address: "Postman@CloudMailin.NET", LocalPart: "Postman", User: "Postman",
+			Domain: "cloudmailin.net",
+		}},
+		{"reply+a+b@example.com", EnvelopeAddress{
+			Address: "reply+a+b@example.com", LocalPart: "reply+a+b", User: "reply",
+			Tag: "a+b", Domain: "example.com",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := ParseEnvelopeAddress(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(tt.expected, got) {
+				t.Errorf("Unexpected address {%v}", cmp.Diff(tt.expected, got))
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "example.com", "@example.com", "user@"} {
+		if _, err := ParseEnvelopeAddress(invalid); err == nil {
+			t.Errorf("Expected error for {%s}", invalid)
+		}
+	}
+
+	t.Run("Base", func(t *testing.T) {
+		address, _ := ParseEnvelopeAddress("reply+1234@Example.com")
+		if address.Base() != "reply@example.com" {
+			t.Errorf("Unexpected base {%s}", address.Base())
+		}
+	})
+}
+
+func TestIncomingMailEnvelope_Addresses(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	sender, err := message.Envelope.Sender()
+	if err != nil || sender.Tag != "test" || sender.User != "from" {
+		t.Errorf("Unexpected sender {%v}, {%v}", sender, err)
+	}
+
+	recipient, err := message.Envelope.Recipient()
+	if err != nil || recipient.Base() != "postman@cloudmailin.net" {
+		t.Errorf("Unexpected recipient {%v}, {%v}", recipient, err)
+	}
+
+	recipients, err := message.Envelope.RecipientAddresses()
+	if err != nil || len(recipients) != 1 || recipients[0].Domain != "cloudmailin.net" {
+		t.Errorf("Unexpected recipients {%v}, {%v}", recipients, err)
+	}
+
+	t.Run("Null sender", func(t *testing.T) {
+		_, err := IncomingMailEnvelope{From: "<>"}.Sender()
+		if !errors.Is(err, ErrNullSender) {
+			t.Errorf("Expected ErrNullSender got {%v}", err)
+		}
+	})
+}
@@ -2,16 +2,21 @@ package cloudmailin
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
-	defaultBaseURL = "https://api.cloudmailin.com/api/v0.1"
+	defaultBaseURL   = "https://api.cloudmailin.com/api/v0.1"
+	defaultUserAgent = "cloudmailin-go"
 )
 
 // Client is the base of an API Client for CloudMailin. It provides a struct to
@@ -22,9 +27,36 @@ type Client struct {
 	SMTPToken     string
 	SMTPAccountID string
 
-	// For future use with the API
+	// Credentials for the account API, see ListAddresses.
 	AccountID    string
 	AccountToken string
+
+	// RetryPolicy controls retrying of transient failures, when nil each
+	// request is attempted once.
+	RetryPolicy *RetryPolicy
+
+	// IdempotencyStore records the OutboundMail.IdempotencyKey values that
+	// have been acknowledged so that sending a message with the same key
+	// doesn't deliver it twice, when nil only the Idempotency-Key header is
+	// sent.
+	IdempotencyStore IdempotencyStore
+
+	// UserAgent is sent with each request if set.
+	UserAgent string
+
+	// Logger receives diagnostic messages such as retries, when nil nothing
+	// is logged.
+	Logger Logger
+
+	// PlainFromHTML fills in the Plain body of an OutboundMail that only has
+	// an HTML body using HTMLToText before it is sent.
+	PlainFromHTML bool
+}
+
+// Logger is the interface used by the Client for diagnostic messages. It is
+// satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
 }
 
 // NewClientFromURL returns an instance of the Client using the provided SMTP URL.
@@ -35,12 +67,10 @@ func NewClientFromURL(smtpURL string) (client Client, err error) {
 	var smtpAccount string
 
 	if smtpURL != "" {
-		u, err := url.Parse(smtpURL)
+		smtpAccount, smtpToken, err = parseSMTPURL(smtpURL)
 		if err != nil {
-			return client, err
+			return
 		}
-		smtpAccount = u.User.Username()
-		smtpToken, _ = u.User.Password()
 	}
 
 	baseURL := os.Getenv("CLOUDMAILIN_API_BASE_URL")
@@ -57,10 +87,37 @@ func NewClientFromURL(smtpURL string) (client Client, err error) {
 		AccountToken:  "",
 	}
 
-	if client.SMTPAccountID == "" || client.SMTPToken == "" {
-		err = fmt.Errorf("missing client values: %v", client)
+	var missing []string
+	if client.SMTPAccountID == "" {
+		missing = append(missing, "SMTPAccountID")
+	}
+	if client.SMTPToken == "" {
+		missing = append(missing, "SMTPToken")
+	}
+	if len(missing) > 0 {
+		err = fmt.Errorf("missing client values: %s", strings.Join(missing, ", "))
+	}
+
+	return
+}
+
+// parseSMTPURL returns the credentials from a CloudMailin SMTP URL. Any
+// error is returned without the URL so that the token isn't exposed.
+func parseSMTPURL(smtpURL string) (account string, token string, err error) {
+	u, err := url.Parse(smtpURL)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		// An escape error quotes part of the URL which may be the token.
+		if _, ok := err.(url.EscapeError); ok {
+			err = errors.New("invalid URL escape")
+		}
+		return "", "", fmt.Errorf("invalid SMTP URL: %w", err)
 	}
 
+	account = u.User.Username()
+	token, _ = u.User.Password()
 	return
 }
 
@@ -80,8 +137,7 @@ func NewClient() (client Client, err error) {
 type RequestType string
 
 const (
-	// RequestTypeAccount is used for account API calls
-	// (currently not implemented).
+	// RequestTypeAccount is used for account API calls.
 	RequestTypeAccount RequestType = "account"
 
 	// RequestTypeSMTP is used for SMTP related API calls.
@@ -92,9 +148,121 @@ const (
 // SMTP credentials work in a really similar way to normal API credentials
 // but use the SMTP Server ID instead of the AccountID and the SMTP Password
 // as the Authorization Bearer Token.
+//
+// The body is encoded as JSON, pass nil to send a request without a body.
+// To add query parameters or decode the response use NewRequest and
+// DoRequest instead.
+//
+// Do uses context.Background, see DoContext to control cancellation.
 func (client Client) Do(method string, path string, body interface{}, kind RequestType) (
 	res *http.Response, err error) {
 
+	return client.DoContext(context.Background(), method, path, body, kind)
+}
+
+// DoContext performs the HTTP request in the same way as Do but attaches ctx
+// to the outgoing request. Cancelling ctx or reaching its deadline will abort
+// the request and any values stored in ctx are available to the transport.
+//
+// If the API responds with a status of 400 or above the response is returned
+// along with an *APIError describing the failure. When the Client has a
+// RetryPolicy transient failures are retried before an error is returned.
+func (client Client) DoContext(ctx context.Context, method string, path string,
+	body interface{}, kind RequestType) (res *http.Response, err error) {
+
+	req, err := client.NewRequest(ctx, method, path, nil, body, kind)
+	if err != nil {
+		return
+	}
+
+	res, err = client.do(req)
+	return
+}
+
+// DoRequest sends a request created by NewRequest. If out is not nil a
+// successful JSON response is decoded into it and the response body is
+// closed, otherwise the caller is responsible for closing the body.
+//
+// Errors are handled in the same way as DoContext.
+func (client Client) DoRequest(req *http.Request, out interface{}) (
+	res *http.Response, err error) {
+
+	res, err = client.do(req)
+	if err != nil || out == nil {
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNoContent {
+		return
+	}
+
+	err = json.NewDecoder(res.Body).Decode(out)
+	if err == io.EOF {
+		err = nil
+	}
+
+	return
+}
+
+// do sends req, retrying according to the RetryPolicy. The request body is
+// replayed for each attempt.
+func (client Client) do(req *http.Request) (res *http.Response, err error) {
+	policy := RetryPolicy{MaxAttempts: 1}
+	if client.RetryPolicy != nil {
+		policy = *client.RetryPolicy
+	}
+
+	attempt := 1
+	for ; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				break
+			}
+		}
+
+		res, err = client.HTTPClient.Do(req)
+		if err == nil && res.StatusCode >= 400 {
+			err = newAPIError(res)
+		}
+
+		if err == nil || attempt >= policy.MaxAttempts || !retryableRequest(req, res, err) ||
+			req.Context().Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			break
+		}
+
+		delay := policy.backoff(attempt, res)
+		if delay < 0 {
+			break
+		}
+
+		if client.Logger != nil {
+			client.Logger.Printf("cloudmailin: retrying %s %s in %v after attempt %d: %v",
+				req.Method, req.URL.Path, delay, attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return res, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if err != nil && attempt > 1 {
+		err = &RetryError{Attempts: attempt, Err: err}
+	}
+
+	return
+}
+
+// NewRequest builds an *http.Request for an API call using the credentials
+// for kind. The path is relative to the account, query is added to the URL
+// (if not nil) and body is encoded as JSON unless it is nil.
+func (client Client) NewRequest(ctx context.Context, method string, path string,
+	query url.Values, body interface{}, kind RequestType) (req *http.Request, err error) {
+
 	var account, token string
 
 	if kind == RequestTypeSMTP {
@@ -106,7 +274,7 @@ func (client Client) Do(method string, path string, body interface{}, kind Reque
 	}
 
 	if account == "" || token == "" {
-		err = fmt.Errorf("account or token credentials are missing for request (%s, %d)",
+		err = fmt.Errorf("%w for request (%s, %d)", ErrMissingCredentials,
 			account, len(token))
 		return
 	}
@@ -114,21 +282,35 @@ func (client Client) Do(method string, path string, body interface{}, kind Reque
 	url := strings.TrimSuffix(client.BaseURL, "/") + "/" + account + "/" +
 		strings.TrimPrefix(path, "/")
 
-	msgJSON, err := json.Marshal(body)
-	if err != nil {
-		return
+	if len(query) > 0 {
+		separator := "?"
+		if strings.Contains(url, "?") {
+			separator = "&"
+		}
+		url += separator + query.Encode()
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(msgJSON))
-	if err != nil {
-		return
+	var reader io.Reader
+	if body != nil {
+		msgJSON, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(msgJSON)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	res, err = client.HTTPClient.Do(req)
+
+	req, err = http.NewRequestWithContext(ctx, method, url, reader)
 	if err != nil {
 		return
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if client.UserAgent != "" {
+		req.Header.Set("User-Agent", client.UserAgent)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	return
 }
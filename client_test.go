@@ -1,9 +1,16 @@
 package cloudmailin
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -148,3 +155,161 @@ func TestNewClientFromURL(t *testing.T) {
 		}
 	})
 }
+
+func TestClient_DoContext(t *testing.T) {
+	type ctxKey struct{}
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.DoContext(ctx, "POST", "/messages", nil, RequestTypeSMTP)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled got {%v}", err)
+		}
+	})
+
+	t.Run("Deadline exceeded", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+
+		client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.DoContext(ctx, "POST", "/messages", nil, RequestTypeSMTP)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded got {%v}", err)
+		}
+	})
+
+	t.Run("Request scoped values", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		var got interface{}
+		client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			got = r.Context().Value(ctxKey{})
+			return http.DefaultTransport.RoundTrip(r)
+		})
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+		res, err := client.DoContext(ctx, "POST", "/messages", nil, RequestTypeSMTP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if got != "value" {
+			t.Errorf("Expected context value to reach transport got {%v}", got)
+		}
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestClient_Do_Methods(t *testing.T) {
+	var method, contentType string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+
+	tests := []struct {
+		name         string
+		method       string
+		body         interface{}
+		expectedBody string
+	}{
+		{"GET without body", "GET", nil, ""},
+		{"DELETE without body", "DELETE", nil, ""},
+		{"PATCH with body", "PATCH", map[string]string{"a": "b"}, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.Do(tt.method, "/addresses/1", tt.body, RequestTypeAccount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if method != tt.method || string(body) != tt.expectedBody {
+				t.Errorf("Expected %s {%s} got %s {%s}", tt.method, tt.expectedBody, method, body)
+			}
+
+			if (tt.body == nil) != (contentType == "") {
+				t.Errorf("Unexpected Content-Type {%s}", contentType)
+			}
+		})
+	}
+}
+
+func TestClient_DoRequest(t *testing.T) {
+	var rawQuery, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+		path = r.URL.Path
+		w.Write([]byte(`{"id":"1","name":"example"}`))
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+
+	req, err := client.NewRequest(context.Background(), "GET", "/addresses",
+		url.Values{"page": {"2"}}, nil, RequestTypeAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if _, err := client.DoRequest(req, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		if path != "/account/addresses" || rawQuery != "page=2" {
+			t.Errorf("Unexpected URL {%s?%s}", path, rawQuery)
+		}
+	})
+
+	t.Run("Decodes response", func(t *testing.T) {
+		if out.ID != "1" || out.Name != "example" {
+			t.Errorf("Unexpected response {%+v}", out)
+		}
+	})
+
+	t.Run("Merges query into path", func(t *testing.T) {
+		req, _ := client.NewRequest(context.Background(), "GET", "/addresses?a=1",
+			url.Values{"b": {"2"}}, nil, RequestTypeAccount)
+		if req.URL.RawQuery != "a=1&b=2" {
+			t.Errorf("Unexpected query {%s}", req.URL.RawQuery)
+		}
+	})
+}
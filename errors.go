@@ -0,0 +1,121 @@
+package cloudmailin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMissingCredentials is returned by Do when the account or token
+	// required for the RequestType has not been set on the Client.
+	ErrMissingCredentials = errors.New("account or token credentials are missing")
+
+	// ErrUnauthorized matches an APIError caused by invalid or insufficient
+	// credentials (HTTP 401 or 403).
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrRateLimited matches an APIError caused by too many requests
+	// (HTTP 429).
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrValidation matches an APIError caused by the API rejecting the
+	// request content (HTTP 400 or 422).
+	ErrValidation = errors.New("validation failed")
+)
+
+// APIErrorBody is the JSON error document returned by the CloudMailin API.
+type APIErrorBody struct {
+	Error   string   `json:"error"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// APIError is returned when the CloudMailin API responds with an
+// unsuccessful status code. Use errors.Is with ErrUnauthorized,
+// ErrRateLimited or ErrValidation to check the class of error or errors.As
+// to inspect the details.
+type APIError struct {
+	// The HTTP status code returned by the API.
+	StatusCode int
+
+	// The decoded error document, this will be empty if the response
+	// wasn't a JSON error.
+	Body APIErrorBody
+
+	// The raw response body.
+	RawBody []byte
+
+	// The request ID returned by the API (if any) to quote to support.
+	RequestID string
+
+	// Retryable is true when the same request may succeed if sent again.
+	Retryable bool
+}
+
+// newAPIError builds an APIError from res. The response body is read and
+// replaced so that callers can still read it.
+func newAPIError(res *http.Response) *APIError {
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		RawBody:    body,
+		RequestID:  res.Header.Get("X-Request-Id"),
+		Retryable:  retryableStatus(res.StatusCode),
+	}
+	_ = json.Unmarshal(body, &apiErr.Body)
+
+	return apiErr
+}
+
+// Error returns a description of the error including the status code and
+// the most descriptive message available from the response.
+func (e *APIError) Error() string {
+	message := e.Body.Message
+	if message == "" {
+		message = e.Body.Error
+	}
+	if len(e.Body.Errors) > 0 {
+		message = strings.TrimPrefix(message+": "+strings.Join(e.Body.Errors, ", "), ": ")
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(e.RawBody))
+	}
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+
+	return fmt.Sprintf("api request failed (%d): %s", e.StatusCode, message)
+}
+
+// Is allows the APIError to be compared to the sentinel errors using
+// errors.Is.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// retryableStatus reports whether a response with status code may succeed
+// if the request is repeated.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
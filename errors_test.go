@@ -0,0 +1,119 @@
+package cloudmailin
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		target   error
+		expected bool
+	}{
+		{"401 Unauthorized", 401, ErrUnauthorized, true},
+		{"403 Unauthorized", 403, ErrUnauthorized, true},
+		{"429 RateLimited", 429, ErrRateLimited, true},
+		{"400 Validation", 400, ErrValidation, true},
+		{"422 Validation", 422, ErrValidation, true},
+		{"422 Unauthorized", 422, ErrUnauthorized, false},
+		{"500 Validation", 500, ErrValidation, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := error(&APIError{StatusCode: tt.status})
+			if errors.Is(err, tt.target) != tt.expected {
+				t.Errorf("Expected errors.Is to be %t for %d", tt.expected, tt.status)
+			}
+		})
+	}
+}
+
+func TestClient_DoContext_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(422)
+		w.Write([]byte(`{"error":"Unprocessable Entity","message":"from is invalid","errors":["from"]}`))
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+	message := buildMessage()
+	res, err := client.SendMail(&message)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError got {%v}", err)
+	}
+
+	t.Run("Fields", func(t *testing.T) {
+		expected := APIErrorBody{
+			Error:   "Unprocessable Entity",
+			Message: "from is invalid",
+			Errors:  []string{"from"},
+		}
+		if !cmp.Equal(expected, apiErr.Body) {
+			t.Errorf("Unexpected body {%v}", cmp.Diff(expected, apiErr.Body))
+		}
+
+		if apiErr.StatusCode != 422 || apiErr.RequestID != "req-123" || apiErr.Retryable {
+			t.Errorf("Unexpected error values {%+v}", apiErr)
+		}
+	})
+
+	t.Run("Error message", func(t *testing.T) {
+		expected := "api request failed (422): from is invalid: from"
+		if err.Error() != expected {
+			t.Errorf("Expected {%s} got {%s}", expected, err)
+		}
+	})
+
+	t.Run("Is validation", func(t *testing.T) {
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation got {%v}", err)
+		}
+	})
+
+	t.Run("Body still readable", func(t *testing.T) {
+		body, _ := ioutil.ReadAll(res.Body)
+		if !strings.Contains(string(body), "from is invalid") {
+			t.Errorf("Expected response body got {%s}", body)
+		}
+	})
+}
+
+func TestClient_DoContext_APIError_Retryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+	_, err := client.Do("POST", "/messages", nil, RequestTypeSMTP)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Retryable {
+		t.Errorf("Expected retryable *APIError got {%v}", err)
+	}
+
+	if err.Error() != "api request failed (503): busy" {
+		t.Errorf("Unexpected message {%v}", err)
+	}
+}
+
+func TestClient_Do_MissingCredentials(t *testing.T) {
+	client := Client{BaseURL: "http://localhost", SMTPAccountID: "user"}
+	_, err := client.Do("POST", "/messages", nil, RequestTypeSMTP)
+
+	if !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("Expected ErrMissingCredentials got {%v}", err)
+	}
+}
@@ -2,6 +2,9 @@ package cloudmailin_test
 
 import (
 	"fmt"
+	"log"
+	"os"
+	"time"
 
 	"github.com/cloudmailin/cloudmailin-go"
 )
@@ -51,3 +54,19 @@ func ExampleClient_SendMail() {
 	fmt.Printf("ID: %t, Tags: %s", message.ID != "", message.Tags)
 	// Output: ID: true, Tags: [go api]
 }
+
+func ExampleNew() {
+	// Create a client from explicit settings rather than the environment.
+	// Every setting is validated and all problems are returned together.
+	client, err := cloudmailin.New(
+		cloudmailin.WithSMTPURL(os.Getenv("CLOUDMAILIN_SMTP_URL")),
+		cloudmailin.WithTimeout(10*time.Second),
+		cloudmailin.WithRetryPolicy(cloudmailin.DefaultRetryPolicy()),
+		cloudmailin.WithLogger(log.Default()),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(client.BaseURL)
+}
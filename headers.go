@@ -0,0 +1,297 @@
+package cloudmailin
+
+import (
+	"errors"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoDate is returned by Date when the message has no Date header.
+var ErrNoDate = errors.New("message has no date header")
+
+// dateLayouts are the formats tried when a date is not valid RFC 5322.
+var dateLayouts = []string{
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2-Jan-2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"Mon Jan 2 15:04:05 2006",
+	"Mon Jan 2 15:04:05 MST 2006",
+	"Mon Jan 2 15:04:05 -0700 2006",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05Z07:00",
+	"Mon, 2 Jan 2006 15:04:05",
+}
+
+// obsZones are the offsets of the zone names allowed by RFC 5322. They are
+// replaced before parsing as time.Parse only knows the names used by the
+// local time zone.
+var obsZones = map[string]string{
+	"UT": "+0000", "GMT": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+}
+
+var (
+	dateCommentPattern    = regexp.MustCompile(`\([^()]*\)`)
+	dateWhitespacePattern = regexp.MustCompile(`\s+`)
+	dateObsZonePattern    = regexp.MustCompile(`:\d\d (UT|GMT|[ECMP][SD]T)( |$)`)
+)
+
+// ParseDate parses a date from an email header. As well as RFC 5322 dates
+// it accepts the common variations seen in real email such as missing day
+// names or seconds, two digit years, trailing comments and asctime format.
+// Dates without a time zone are assumed to be UTC. The zone names allowed by
+// RFC 5322 such as EST are supported, other names are an error unless they
+// are used by the local time zone.
+func ParseDate(value string) (time.Time, error) {
+	clean := dateCommentPattern.ReplaceAllString(value, " ")
+	clean = dateWhitespacePattern.ReplaceAllString(strings.TrimSpace(clean), " ")
+	clean = strings.Replace(clean, " ,", ",", -1)
+	clean = strings.TrimSuffix(clean, " GMT+0000")
+	clean = dateObsZonePattern.ReplaceAllStringFunc(clean, func(zone string) string {
+		name := strings.TrimSpace(zone[3:])
+		return strings.Replace(zone, name, obsZones[name], 1)
+	})
+
+	if date, err := mail.ParseDate(clean); err == nil {
+		return checkZone(date, value)
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, clean); err == nil {
+			return checkZone(date, value)
+		}
+	}
+
+	return time.Time{}, errors.New("unrecognized date format: " + value)
+}
+
+// checkZone returns an error if date has a zone name that couldn't be
+// resolved rather than treating it as UTC.
+func checkZone(date time.Time, value string) (time.Time, error) {
+	if name, offset := date.Zone(); offset == 0 && name != "" && name != "UTC" {
+		return time.Time{}, errors.New("unknown time zone " + name + " in date: " + value)
+	}
+	return date, nil
+}
+
+// Date parses the Date header of the message, see ParseDate.
+func (i IncomingMailHeaders) Date() (time.Time, error) {
+	value := i.First("date")
+	if value == "" {
+		return time.Time{}, ErrNoDate
+	}
+	return ParseDate(value)
+}
+
+// ReceivedHop is a single Received header describing one relay the
+// message passed through.
+type ReceivedHop struct {
+	// From is the host the message was received from and FromDetail any
+	// comment following it, usually containing the reverse DNS and IP.
+	From       string
+	FromDetail string
+
+	By   string
+	Via  string
+	With string
+	ID   string
+	For  string
+
+	// Timestamp is zero if the header has no date or it cannot be parsed.
+	Timestamp time.Time
+
+	// Raw is the original header value.
+	Raw string
+}
+
+// Received parses the Received headers in the order the message travelled,
+// from the first server to the last (the reverse of the order they appear
+// in the message).
+func (i IncomingMailHeaders) Received() []ReceivedHop {
+	values := i.Find("received")
+	hops := make([]ReceivedHop, 0, len(values))
+	for n := len(values) - 1; n >= 0; n-- {
+		hops = append(hops, ParseReceived(values[n]))
+	}
+	return hops
+}
+
+// ParseReceived parses a single Received header value.
+func ParseReceived(value string) ReceivedHop {
+	hop := ReceivedHop{Raw: value}
+
+	clauses := value
+	if semi := strings.LastIndex(value, ";"); semi >= 0 {
+		clauses = value[:semi]
+		if date, err := ParseDate(strings.TrimSpace(value[semi+1:])); err == nil {
+			hop.Timestamp = date
+		}
+	}
+
+	fields := map[string]*string{
+		"from": &hop.From,
+		"by":   &hop.By,
+		"via":  &hop.Via,
+		"with": &hop.With,
+		"id":   &hop.ID,
+		"for":  &hop.For,
+	}
+
+	var current string
+	for _, token := range receivedTokens(clauses) {
+		if strings.HasPrefix(token, "(") {
+			if current == "from" && hop.FromDetail == "" {
+				// The comment may be unclosed at the end of the header.
+				detail := strings.TrimSuffix(token[1:], ")")
+				hop.FromDetail = strings.TrimSpace(detail)
+			}
+			continue
+		}
+
+		if field, ok := fields[strings.ToLower(token)]; ok && *field == "" {
+			current = strings.ToLower(token)
+			continue
+		}
+
+		if current != "" && *fields[current] == "" {
+			*fields[current] = strings.Trim(token, "<>")
+		}
+	}
+
+	return hop
+}
+
+// receivedTokens splits a Received header into words, keeping comments in
+// parentheses as a single token.
+func receivedTokens(value string) []string {
+	var tokens []string
+	depth, start := 0, -1
+
+	for i, r := range value {
+		switch {
+		case r == '(':
+			if depth == 0 {
+				if start >= 0 {
+					tokens = append(tokens, value[start:i])
+				}
+				start = i
+			}
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				tokens = append(tokens, value[start:i+1])
+				start = -1
+			}
+		case depth > 0:
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			if start >= 0 {
+				tokens = append(tokens, value[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, value[start:])
+	}
+	return tokens
+}
+
+// HeaderName converts a key used in IncomingMailHeaders, such as message_id,
+// to its canonical header name (Message-Id).
+func HeaderName(key string) string {
+	return textproto.CanonicalMIMEHeaderKey(strings.Replace(key, "_", "-", -1))
+}
+
+// Each calls fn for each header value with the canonical header name until
+// fn returns false. Headers are visited in order of their name, with the
+// values of each header in the order they appear in the message.
+func (i IncomingMailHeaders) Each(fn func(name, value string) bool) {
+	i.each(nil, fn)
+}
+
+// EachHeader calls fn for each header value in the order the headers were
+// received until fn returns false. Each value is given with its canonical
+// header name.
+//
+// The order is only known for messages parsed from JSON, otherwise this is
+// the same as Headers.Each.
+func (m IncomingMail) EachHeader(fn func(name, value string) bool) {
+	m.Headers.each(m.HeaderOrder, fn)
+}
+
+// each visits the keys in order, followed by any remaining keys sorted by
+// name.
+func (i IncomingMailHeaders) each(order []string, fn func(name, value string) bool) {
+	seen := make(map[string]bool, len(i))
+	keys := make([]string, 0, len(i))
+	for _, key := range order {
+		if _, ok := i[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	rest := make([]string, 0, len(i)-len(keys))
+	for key := range i {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, key := range append(keys, rest...) {
+		name := HeaderName(key)
+		for _, value := range i[key] {
+			if !fn(name, value) {
+				return
+			}
+		}
+	}
+}
+
+// MIMEHeader converts the headers to a textproto.MIMEHeader using the
+// canonical header names.
+func (i IncomingMailHeaders) MIMEHeader() textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader, len(i))
+	for key, values := range i {
+		name := HeaderName(key)
+		header[name] = append(header[name], values...)
+	}
+	return header
+}
+
+// MailHeader converts the headers to a mail.Header, see MIMEHeader.
+func (i IncomingMailHeaders) MailHeader() mail.Header {
+	return mail.Header(i.MIMEHeader())
+}
+
+// HeadersFromMIME converts a textproto.MIMEHeader to IncomingMailHeaders
+// using CloudMailin's keys. Values are copied without decoding.
+func HeadersFromMIME(header textproto.MIMEHeader) IncomingMailHeaders {
+	headers := make(IncomingMailHeaders, len(header))
+	for name, values := range header {
+		key := headerKey(name)
+		headers[key] = append(headers[key], values...)
+	}
+	return headers
+}
+
+// HeadersFromMail converts a mail.Header to IncomingMailHeaders, see
+// HeadersFromMIME.
+func HeadersFromMail(header mail.Header) IncomingMailHeaders {
+	return HeadersFromMIME(textproto.MIMEHeader(header))
+}
@@ -0,0 +1,298 @@
+package cloudmailin
+
+import (
+	"net/textproto"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDate(t *testing.T) {
+	expected := time.Date(2020, 7, 8, 10, 44, 51, 0, time.FixedZone("", 3600))
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"RFC 5322", "Wed, 08 Jul 2020 10:44:51 +0100"},
+		{"No Weekday", "8 Jul 2020 10:44:51 +0100"},
+		{"Comment", "Wed, 08 Jul 2020 10:44:51 +0100 (BST)"},
+		{"Extra Whitespace", "Wed,  8 Jul 2020   10:44:51 +0100"},
+		{"Two Digit Year", "Wed, 08 Jul 20 10:44:51 +0100"},
+		{"Full Month", "Wed, 08 July 2020 10:44:51 +0100"},
+		{"Dashes", "Wed, 08-Jul-2020 10:44:51 +0100"},
+		{"ISO", "2020-07-08 10:44:51 +0100"},
+		{"RFC 3339", "2020-07-08T10:44:51+01:00"},
+		{"asctime Zone", "Wed Jul 8 10:44:51 +0100 2020"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := ParseDate(tt.value)
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was {%v}", err)
+			}
+			if !date.Equal(expected) {
+				t.Errorf("Expected {%v} got {%v}", expected, date)
+			}
+		})
+	}
+
+	t.Run("No Seconds", func(t *testing.T) {
+		date, err := ParseDate("Wed, 8 Jul 2020 10:44 +0100")
+		if err != nil || !date.Equal(expected.Add(-51*time.Second)) {
+			t.Errorf("Expected {%v} got {%v} (%v)", expected, date, err)
+		}
+	})
+
+	t.Run("No Zone", func(t *testing.T) {
+		date, err := ParseDate("Wed Jul  8 09:44:51 2020")
+		if err != nil || !date.Equal(expected) {
+			t.Errorf("Expected {%v} got {%v} (%v)", expected, date, err)
+		}
+	})
+
+	t.Run("Obsolete Zones", func(t *testing.T) {
+		tests := []struct {
+			value  string
+			offset int
+		}{
+			{"Wed, 08 Jul 2020 04:44:51 EST", -5 * 60 * 60},
+			{"Wed, 08 Jul 2020 02:44:51 PDT", -7 * 60 * 60},
+			{"Wed, 08 Jul 2020 09:44:51 GMT", 0},
+			{"Wed, 08 Jul 2020 09:44:51 UT", 0},
+			{"Wed Jul 8 05:44:51 EDT 2020", -4 * 60 * 60},
+		}
+
+		for _, tt := range tests {
+			date, err := ParseDate(tt.value)
+			if _, offset := date.Zone(); err != nil || !date.Equal(expected) || offset != tt.offset {
+				t.Errorf("Expected {%v} got {%v} (%v) for %s", expected, date, err, tt.value)
+			}
+		}
+	})
+
+	t.Run("Unknown Zone", func(t *testing.T) {
+		for _, value := range []string{"Wed, 08 Jul 2020 11:44:51 XYZ", "Wed Jul 8 10:44:51 XST 2020"} {
+			if date, err := ParseDate(value); err == nil {
+				t.Errorf("Expected error for %s got {%v}", value, date)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := ParseDate("yesterday"); err == nil {
+			t.Error("Expected error but was nil")
+		}
+	})
+}
+
+func TestIncomingMailHeaders_Date(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	date, err := message.Headers.Date()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was {%v}", err)
+	}
+	if expected := time.Date(2020, 7, 8, 9, 44, 51, 0, time.UTC); !date.Equal(expected) {
+		t.Errorf("Expected {%v} got {%v}", expected, date)
+	}
+
+	if _, err := (IncomingMailHeaders{}).Date(); err != ErrNoDate {
+		t.Errorf("Expected {%v} got {%v}", ErrNoDate, err)
+	}
+}
+
+func TestParseReceived(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected ReceivedHop
+	}{
+		{
+			"Full",
+			"from mail.example.com (mail.example.com [192.0.2.1])\r\n\tby mx.cloudmailin.net " +
+				"(Postfix) with ESMTPS id 4C1D2 for <user@example.net>; Wed, 8 Jul 2020 09:45:03 +0000 (UTC)",
+			ReceivedHop{
+				From:       "mail.example.com",
+				FromDetail: "mail.example.com [192.0.2.1]",
+				By:         "mx.cloudmailin.net",
+				With:       "ESMTPS",
+				ID:         "4C1D2",
+				For:        "user@example.net",
+				Timestamp:  time.Date(2020, 7, 8, 9, 45, 3, 0, time.UTC),
+			},
+		},
+		{
+			"Via",
+			"from relay (HELO relay) by host via HTTP with LMTP",
+			ReceivedHop{From: "relay", FromDetail: "HELO relay", By: "host", Via: "HTTP", With: "LMTP"},
+		},
+		{"No Date", "by localhost", ReceivedHop{By: "localhost"}},
+		{"Bad Date", "by localhost; not a date", ReceivedHop{By: "localhost"}},
+		{"Unclosed Comment", "from 00(", ReceivedHop{From: "00"}},
+		{"Unclosed Comment Text", "from relay (abc", ReceivedHop{From: "relay", FromDetail: "abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hop := ParseReceived(tt.value)
+			tt.expected.Raw = tt.value
+			if !cmp.Equal(tt.expected, hop) {
+				t.Errorf("Expected {%v} got {%v}\n%v", tt.expected, hop, cmp.Diff(tt.expected, hop))
+			}
+		})
+	}
+}
+
+func TestIncomingMailHeaders_Received(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	hops := message.Headers.Received()
+	if len(hops) != 2 {
+		t.Fatalf("Expected {2} got {%v}", len(hops))
+	}
+
+	// The bottom header is the first hop.
+	if hops[0].By != "localhost" {
+		t.Errorf("Expected {localhost} got {%v}", hops[0].By)
+	}
+
+	expected := ReceivedHop{
+		By:        "mail-qv1-f65.google.com",
+		With:      "SMTP",
+		ID:        "p7so20148692qvl.4",
+		For:       "postman@cloudmailin.net",
+		Timestamp: time.Date(2020, 7, 8, 9, 45, 3, 0, time.UTC),
+	}
+	hop := hops[1]
+	hop.Raw = ""
+	if !cmp.Equal(expected, hop, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })) {
+		t.Errorf("Expected {%v} got {%v}", expected, hop)
+	}
+
+	date, _ := message.Headers.Date()
+	if transit := hops[1].Timestamp.Sub(date); transit != 12*time.Second {
+		t.Errorf("Expected {12s} got {%v}", transit)
+	}
+}
+
+func TestIncomingMailHeaders_FindCanonical(t *testing.T) {
+	headers := IncomingMailHeaders{
+		"message_id":  {"<id@example.com>"},
+		"X-Custom-ID": {"custom"},
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		expected IncomingMailHeader
+	}{
+		{"Key", "message_id", IncomingMailHeader{"<id@example.com>"}},
+		{"RFC Name", "Message-ID", IncomingMailHeader{"<id@example.com>"}},
+		{"Canonical", "Message-Id", IncomingMailHeader{"<id@example.com>"}},
+		{"Lowercase", "message-id", IncomingMailHeader{"<id@example.com>"}},
+		{"Unnormalized Key", "x_custom_id", IncomingMailHeader{"custom"}},
+		{"Missing", "Subject", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if item := headers.Find(tt.key); !cmp.Equal(tt.expected, item) {
+				t.Errorf("Expected {%v} got {%v}", tt.expected, item)
+			}
+		})
+	}
+}
+
+func TestIncomingMail_EachHeader(t *testing.T) {
+	data := `{"headers": {"to": "a@example.com", "received": ["Received 2", "Received 1"],
+		"date": "Wed, 08 Jul 2020 10:44:51 +0100", "message_id": "<id@example.com>"}}`
+
+	message, err := ParseIncomingBytes([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was {%v}", err)
+	}
+
+	type field struct{ Name, Value string }
+	var fields []field
+	message.EachHeader(func(name, value string) bool {
+		fields = append(fields, field{name, value})
+		return true
+	})
+
+	expected := []field{
+		{"To", "a@example.com"},
+		{"Received", "Received 2"},
+		{"Received", "Received 1"},
+		{"Date", "Wed, 08 Jul 2020 10:44:51 +0100"},
+		{"Message-Id", "<id@example.com>"},
+	}
+	if !cmp.Equal(expected, fields) {
+		t.Errorf("Expected {%v} got {%v}", expected, fields)
+	}
+
+	t.Run("Stop", func(t *testing.T) {
+		count := 0
+		message.EachHeader(func(name, value string) bool {
+			count++
+			return name != "Received"
+		})
+		if count != 2 {
+			t.Errorf("Expected {2} got {%v}", count)
+		}
+	})
+
+	t.Run("Sorted Without Order", func(t *testing.T) {
+		var names []string
+		message.Headers.Each(func(name, value string) bool {
+			names = append(names, name)
+			return true
+		})
+		expected := []string{"Date", "Message-Id", "Received", "Received", "To"}
+		if !cmp.Equal(expected, names) {
+			t.Errorf("Expected {%v} got {%v}", expected, names)
+		}
+	})
+
+	t.Run("Null Headers", func(t *testing.T) {
+		message, err := ParseIncomingBytes([]byte(`{"headers": null, "plain": "Hi"}`))
+		if err != nil || message.Headers != nil || message.Plain != "Hi" {
+			t.Errorf("Expected empty headers got {%v} (%v)", message.Headers, err)
+		}
+	})
+}
+
+func TestIncomingMailHeaders_MIMEHeader(t *testing.T) {
+	headers := IncomingMailHeaders{
+		"message_id": {"<id@example.com>"},
+		"received":   {"Received 2", "Received 1"},
+	}
+
+	mime := headers.MIMEHeader()
+	expected := textproto.MIMEHeader{
+		"Message-Id": {"<id@example.com>"},
+		"Received":   {"Received 2", "Received 1"},
+	}
+	if !cmp.Equal(expected, mime) {
+		t.Errorf("Expected {%v} got {%v}", expected, mime)
+	}
+
+	if id := headers.MailHeader().Get("Message-ID"); id != "<id@example.com>" {
+		t.Errorf("Expected {<id@example.com>} got {%v}", id)
+	}
+
+	if back := HeadersFromMIME(mime); !cmp.Equal(headers, back) {
+		t.Errorf("Expected {%v} got {%v}", headers, back)
+	}
+
+	if back := HeadersFromMail(headers.MailHeader()); !cmp.Equal(headers, back) {
+		t.Errorf("Expected {%v} got {%v}", headers, back)
+	}
+}
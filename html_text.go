@@ -0,0 +1,383 @@
+package cloudmailin
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// HTMLToText converts an HTML email body to plain text suitable for the
+// text alternative of an email.
+//
+// Headings, paragraphs and other blocks are separated by blank lines, list
+// items are bulleted or numbered, table cells are separated by " | " and
+// blockquotes are quoted with ">". Links are numbered and their URLs listed
+// as footnotes at the end of the text. Whitespace is collapsed except within
+// pre elements and the content of script, style and head elements is
+// dropped.
+func HTMLToText(body string) string {
+	c := htmlConverter{}
+	c.convert(body)
+	return c.String()
+}
+
+// Text returns the Plain body of the email or, if there isn't one, the HTML
+// body converted with HTMLToText.
+func (m IncomingMail) Text() string {
+	if strings.TrimSpace(m.Plain) != "" || m.HTML == "" {
+		return m.Plain
+	}
+	return HTMLToText(m.HTML)
+}
+
+// htmlBlockNewlines lists the elements that start on a new line and how
+// many newlines separate them from the surrounding text, two leaves a
+// blank line.
+var htmlBlockNewlines = map[string]int{
+	"address": 1, "article": 2, "aside": 2, "blockquote": 2, "dd": 1, "div": 1,
+	"dl": 2, "dt": 1, "fieldset": 2, "figcaption": 1, "figure": 2, "footer": 1,
+	"form": 1, "h1": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2,
+	"header": 1, "hr": 2, "li": 1, "main": 1, "nav": 1, "ol": 2, "p": 2,
+	"pre": 2, "section": 2, "table": 2, "tr": 1, "ul": 2,
+}
+
+// htmlSkipped lists the elements whose content isn't shown.
+var htmlSkipped = map[string]bool{
+	"head": true, "script": true, "style": true, "template": true, "title": true,
+}
+
+var (
+	htmlTagNamePattern = regexp.MustCompile(`^</?([a-zA-Z][a-zA-Z0-9]*)`)
+	htmlAttrPattern    = regexp.MustCompile(
+		`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+type htmlList struct {
+	ordered bool
+	count   int
+}
+
+type htmlConverter struct {
+	out strings.Builder
+
+	newlines  int
+	space     bool
+	lineStart bool
+
+	skip       string
+	pre        int
+	quotes     int
+	lineQuotes int
+	lists      []htmlList
+	cells      int
+
+	links     []string
+	linkHref  string
+	linkStart int
+}
+
+// convert tokenizes body, calling text and tag for each token.
+func (c *htmlConverter) convert(body string) {
+	c.lineStart = true
+
+	for body != "" {
+		lt := strings.IndexByte(body, '<')
+		if lt < 0 {
+			c.text(body)
+			return
+		}
+		if lt > 0 {
+			c.text(body[:lt])
+			body = body[lt:]
+		}
+
+		switch {
+		case strings.HasPrefix(body, "<!--"):
+			end := strings.Index(body, "-->")
+			if end < 0 {
+				return
+			}
+			body = body[end+3:]
+		case strings.HasPrefix(body, "<!") || strings.HasPrefix(body, "<?"):
+			end := strings.IndexByte(body, '>')
+			if end < 0 {
+				return
+			}
+			body = body[end+1:]
+		default:
+			match := htmlTagNamePattern.FindStringSubmatch(body)
+			if match == nil {
+				c.text("<")
+				body = body[1:]
+				continue
+			}
+			end := tagEnd(body)
+			if end < 0 {
+				return
+			}
+			c.tag(strings.ToLower(match[1]), body[len(match[0]):end],
+				strings.HasPrefix(body, "</"))
+			body = body[end+1:]
+		}
+	}
+}
+
+// tagEnd returns the index of the > closing the tag at the start of body,
+// ignoring any within quoted attribute values.
+func tagEnd(body string) int {
+	var quote byte
+	for i := 1; i < len(body); i++ {
+		switch {
+		case quote != 0:
+			if body[i] == quote {
+				quote = 0
+			}
+		case body[i] == '"' || body[i] == '\'':
+			quote = body[i]
+		case body[i] == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// attr returns the value of the attribute name from the attributes of a
+// tag.
+func attr(attrs string, name string) string {
+	for _, match := range htmlAttrPattern.FindAllStringSubmatch(attrs, -1) {
+		if strings.EqualFold(match[1], name) {
+			return html.UnescapeString(match[2] + match[3] + match[4])
+		}
+	}
+	return ""
+}
+
+func (c *htmlConverter) tag(name string, attrs string, closing bool) {
+	if c.skip != "" {
+		if closing && name == c.skip {
+			c.skip = ""
+		}
+		return
+	}
+	if htmlSkipped[name] && !closing {
+		if !strings.HasSuffix(strings.TrimSpace(attrs), "/") {
+			c.skip = name
+		}
+		return
+	}
+
+	if n, ok := htmlBlockNewlines[name]; ok {
+		// Lists nested in another list aren't separated by blank lines.
+		if (name == "ul" || name == "ol") &&
+			(!closing && len(c.lists) > 0 || closing && len(c.lists) > 1) {
+			n = 1
+		}
+		c.block(n)
+	}
+
+	switch name {
+	case "br":
+		c.newlines++
+		c.space = false
+	case "hr":
+		if !closing {
+			c.write("----")
+			c.block(2)
+		}
+	case "pre":
+		if closing && c.pre > 0 {
+			c.pre--
+		} else if !closing {
+			c.pre++
+		}
+	case "blockquote":
+		if closing && c.quotes > 0 {
+			c.quotes--
+		} else if !closing {
+			c.quotes++
+		}
+	case "ul", "ol":
+		if closing {
+			if len(c.lists) > 0 {
+				c.lists = c.lists[:len(c.lists)-1]
+			}
+		} else {
+			c.lists = append(c.lists, htmlList{ordered: name == "ol"})
+		}
+	case "li":
+		if closing {
+			return
+		}
+		marker := "*"
+		depth := len(c.lists)
+		if depth > 0 {
+			list := &c.lists[depth-1]
+			list.count++
+			if list.ordered {
+				marker = fmt.Sprintf("%d.", list.count)
+			}
+			depth--
+		}
+		c.write(strings.Repeat("  ", depth) + marker + " ")
+	case "tr":
+		c.cells = 0
+	case "td", "th":
+		if !closing {
+			if c.cells > 0 {
+				c.write(" | ")
+			}
+			c.cells++
+		}
+	case "h1", "h2":
+		if closing {
+			c.underline(map[string]string{"h1": "=", "h2": "-"}[name])
+		}
+	case "img":
+		if alt := strings.TrimSpace(attr(attrs, "alt")); alt != "" {
+			c.text("[" + alt + "]")
+		}
+	case "a":
+		if !closing {
+			c.linkHref = strings.TrimSpace(attr(attrs, "href"))
+			c.linkStart = c.out.Len()
+			return
+		}
+		c.footnote()
+	}
+}
+
+// block ends the current line, leaving n newlines before the next text.
+func (c *htmlConverter) block(n int) {
+	if n > c.newlines {
+		c.newlines = n
+	}
+	c.space = false
+}
+
+// text writes text content, collapsing whitespace outside of pre elements.
+func (c *htmlConverter) text(text string) {
+	if c.skip != "" {
+		return
+	}
+	text = html.UnescapeString(text)
+
+	if c.pre > 0 {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				c.newlines++
+			}
+			c.write(line)
+		}
+		return
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		if text != "" {
+			c.space = true
+		}
+		return
+	}
+
+	if strings.TrimLeft(text[:1], " \t\r\n\f") == "" {
+		c.space = true
+	}
+	c.write(strings.Join(fields, " "))
+	c.space = strings.TrimRight(text[len(text)-1:], " \t\r\n\f") == ""
+}
+
+// write writes s after any pending newlines or space.
+func (c *htmlConverter) write(s string) {
+	if s == "" {
+		return
+	}
+
+	if c.newlines > 0 {
+		if c.out.Len() > 0 {
+			// Blank lines within a blockquote are quoted too.
+			quotes := c.quotes
+			if c.lineQuotes < quotes {
+				quotes = c.lineQuotes
+			}
+			blank := "\n" + strings.TrimSpace(strings.Repeat("> ", quotes))
+			c.out.WriteString(strings.Repeat(blank, c.newlines-1) + "\n")
+			c.lineStart = true
+		}
+		c.newlines = 0
+		c.space = false
+	}
+
+	if c.lineStart {
+		c.out.WriteString(strings.Repeat("> ", c.quotes))
+		c.lineQuotes = c.quotes
+		c.lineStart = false
+	} else if c.space {
+		c.out.WriteString(" ")
+	}
+	c.space = false
+
+	c.out.WriteString(s)
+}
+
+// underline underlines the current line, used for headings.
+func (c *htmlConverter) underline(char string) {
+	line := c.out.String()
+	line = line[strings.LastIndexByte(line, '\n')+1:]
+	line = strings.TrimLeft(line, "> ")
+	if line == "" {
+		return
+	}
+
+	c.newlines = 1
+	c.write(strings.Repeat(char, utf8.RuneCountInString(line)))
+	c.block(2)
+}
+
+// footnote adds the URL of the link that has just closed as a footnote
+// unless it would add nothing to the text.
+func (c *htmlConverter) footnote() {
+	href := c.linkHref
+	c.linkHref = ""
+
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+		return
+	}
+
+	text := strings.TrimSpace(c.out.String()[c.linkStart:])
+	if text == href || "mailto:"+text == href || "tel:"+text == href {
+		return
+	}
+	if text == "" {
+		c.write(href)
+		return
+	}
+
+	c.links = append(c.links, href)
+	c.out.WriteString(fmt.Sprintf(" [%d]", len(c.links)))
+}
+
+// String returns the text with trailing spaces removed and blank lines
+// collapsed, followed by the link footnotes.
+func (c *htmlConverter) String() string {
+	lines := strings.Split(c.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text := strings.Join(lines, "\n")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+	text = strings.TrimSpace(text)
+
+	if len(c.links) > 0 {
+		var footnotes strings.Builder
+		for i, link := range c.links {
+			fmt.Fprintf(&footnotes, "\n[%d] %s", i+1, link)
+		}
+		text += "\n" + footnotes.String()
+	}
+
+	return text
+}
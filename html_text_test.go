@@ -0,0 +1,97 @@
+package cloudmailin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"Fixture", "<div dir=\"ltr\">Test Content<div><br></div></div>\n", "Test Content"},
+		{"Whitespace", "<p>  Hello\n\t  <b>big</b>   world </p>", "Hello big world"},
+		{"Paragraphs", "<p>One</p><p>Two</p>Three<br>Four<br><br>Five", "One\n\nTwo\n\nThree\nFour\n\nFive"},
+		{"Entities", "<p>Fish &amp; chips&nbsp;&lt;3 &#8364;5</p>", "Fish & chips <3 €5"},
+		{
+			"Headings",
+			"<h1>Title</h1><p>Intro</p><h2>Über</h2><h3>Small</h3>Text",
+			"Title\n=====\n\nIntro\n\nÜber\n----\n\nSmall\n\nText",
+		},
+		{
+			"Links",
+			`<p>See <a href="https://example.com/docs">the docs</a> or ` +
+				`<a href="https://example.com">https://example.com</a>, ` +
+				`<a href="mailto:support@example.com">support@example.com</a> ` +
+				`<a href="#top">top</a> <a href='https://example.com/a?b=1&amp;c=2'>again</a></p>`,
+			"See the docs [1] or https://example.com, support@example.com top again [2]\n\n" +
+				"[1] https://example.com/docs\n[2] https://example.com/a?b=1&c=2",
+		},
+		{
+			"Lists",
+			"<ul><li>One</li><li>Two<ol><li>A</li><li>B</li></ol></li></ul><p>After</p>",
+			"* One\n* Two\n  1. A\n  2. B\n\nAfter",
+		},
+		{
+			"Table",
+			"<table><tr><th>Name</th><th>Qty</th></tr><tr><td>Apple</td><td>2</td></tr></table>",
+			"Name | Qty\nApple | 2",
+		},
+		{
+			"Blockquote",
+			"<p>Reply</p><blockquote><p>Quoted</p><p>Text</p></blockquote>",
+			"Reply\n\n> Quoted\n>\n> Text",
+		},
+		{"Pre", "<pre>a  b\n  c</pre><p>d   e</p>", "a  b\n  c\n\nd e"},
+		{
+			"Skipped",
+			"<html><head><title>T</title><style>p { color: red; }</style></head>" +
+				"<body><!-- comment --><script>var a = '<p>';</script><p>Body</p></body></html>",
+			"Body",
+		},
+		{"Image", `<p>Logo: <img src="logo.png" alt="CloudMailin"></p>`, "Logo: [CloudMailin]"},
+		{"Rule", "One<hr>Two", "One\n\n----\n\nTwo"},
+		{"Attribute With Bracket", `<p title="a > b">Text</p>`, "Text"},
+		{"Broken", "a < b <p", "a < b"},
+		{"Empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if text := HTMLToText(tt.html); text != tt.expected {
+				t.Errorf("Expected {%q} got {%q}", tt.expected, text)
+			}
+		})
+	}
+}
+
+func TestIncomingMail_Text(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	if text := message.Text(); text != message.Plain {
+		t.Errorf("Expected {%v} got {%v}", message.Plain, text)
+	}
+
+	message.Plain = ""
+	if text := message.Text(); text != "Test Content" {
+		t.Errorf("Expected {Test Content} got {%v}", text)
+	}
+}
+
+func TestIncomingMail_Reply_HTMLOnly(t *testing.T) {
+	message := IncomingMail{
+		HTML: `<div dir="ltr">Thanks<br></div><br><div class="gmail_quote">` +
+			`<div class="gmail_attr">On Wed, Steve wrote:<br></div><blockquote>Hi</blockquote></div>`,
+	}
+
+	expected := Reply{Text: "Thanks", Quoted: "On Wed, Steve wrote:\n\n> Hi"}
+	if reply := message.Reply(); !cmp.Equal(expected, reply) {
+		t.Errorf("Expected {%v} got {%v}\n%v", expected, reply, cmp.Diff(expected, reply))
+	}
+}
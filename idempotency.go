@@ -0,0 +1,84 @@
+package cloudmailin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// IdempotencyKeyHeader is the HTTP header used to send the
+// OutboundMail.IdempotencyKey to the API.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyStore records the API response for idempotency keys that have
+// been acknowledged with a message ID. When a Client has a store, sending a
+// message with a key that is already recorded returns the recorded result
+// instead of posting the message again.
+//
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Load returns the response body recorded for key.
+	Load(key string) (response []byte, ok bool)
+
+	// Store records the response body for key.
+	Store(key string, response []byte)
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. Records are kept
+// for the life of the process so it only protects against repeated sends
+// within a single instance.
+type MemoryIdempotencyStore struct {
+	// MaxEntries limits the number of records kept, the oldest record is
+	// removed once the limit is reached. Zero means no limit.
+	MaxEntries int
+
+	mu      sync.Mutex
+	records map[string][]byte
+	order   []string
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore keeping
+// at most maxEntries records (zero for no limit).
+func NewMemoryIdempotencyStore(maxEntries int) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{MaxEntries: maxEntries}
+}
+
+// Load returns the response body recorded for key.
+func (s *MemoryIdempotencyStore) Load(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, ok := s.records[key]
+	return response, ok
+}
+
+// Store records the response body for key.
+func (s *MemoryIdempotencyStore) Store(key string, response []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records == nil {
+		s.records = make(map[string][]byte)
+	}
+
+	if _, ok := s.records[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.records[key] = response
+
+	for s.MaxEntries > 0 && len(s.order) > s.MaxEntries {
+		delete(s.records, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// NewIdempotencyKey generates a random key suitable for
+// OutboundMail.IdempotencyKey.
+func NewIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,112 @@
+package cloudmailin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_SendMail_IdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id":"abc123"}`))
+	}))
+	defer server.Close()
+
+	client := Client{
+		BaseURL:          server.URL,
+		SMTPAccountID:    "user",
+		SMTPToken:        "pass",
+		RetryPolicy:      &RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond},
+		IdempotencyStore: NewMemoryIdempotencyStore(0),
+	}
+
+	t.Run("Generated key reused across retries", func(t *testing.T) {
+		message := buildMessage()
+		if _, err := client.SendMail(&message); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+			t.Errorf("Expected the same key for each attempt got {%q}", keys)
+		}
+
+		if message.IdempotencyKey != "" {
+			t.Errorf("Expected generated key not to be kept got {%s}", message.IdempotencyKey)
+		}
+	})
+
+	t.Run("Reused message without a key", func(t *testing.T) {
+		sent := len(keys)
+		message := buildMessage()
+		for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+			message.To = []string{to}
+			if _, err := client.SendMail(&message); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		keys := keys[sent:]
+		if len(keys) != 3 || keys[0] == keys[1] || keys[1] == keys[2] {
+			t.Errorf("Expected 3 requests with different keys got {%q}", keys)
+		}
+
+		if message.To[0] != "c@example.com" {
+			t.Errorf("Expected To to be kept got {%v}", message.To)
+		}
+	})
+
+	t.Run("Caller supplied key", func(t *testing.T) {
+		message := buildMessage()
+		message.IdempotencyKey = "order-1234"
+		if _, err := client.SendMail(&message); err != nil {
+			t.Fatal(err)
+		}
+
+		if keys[len(keys)-1] != "order-1234" {
+			t.Errorf("Expected caller key got {%q}", keys)
+		}
+	})
+
+	t.Run("Repeated key returns original result", func(t *testing.T) {
+		sent := len(keys)
+		message := buildMessage()
+		message.IdempotencyKey = "order-1234"
+		res, err := client.SendMail(&message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(keys) != sent {
+			t.Errorf("Expected no further requests got {%q}", keys[sent:])
+		}
+
+		if message.ID != "abc123" || res.StatusCode != 202 ||
+			res.Header.Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Expected replayed result got {%v}, {%v}", message.ID, res)
+		}
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := NewMemoryIdempotencyStore(2)
+	store.Store("a", []byte("1"))
+	store.Store("b", []byte("2"))
+	store.Store("a", []byte("3"))
+	store.Store("c", []byte("4"))
+
+	if _, ok := store.Load("a"); ok {
+		t.Error("Expected oldest key to be evicted")
+	}
+
+	if got, ok := store.Load("c"); !ok || string(got) != "4" {
+		t.Errorf("Expected c to be stored got {%s}", got)
+	}
+}
@@ -1,10 +1,13 @@
 package cloudmailin
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 )
 
 // IncomingMail represents an email received via HTTP from the
@@ -16,6 +19,57 @@ type IncomingMail struct {
 	HTML        string                   `json:"html"`
 	ReplyPlain  string                   `json:"reply_plain"`
 	Attachments []IncomingMailAttachment `json:"attachments"`
+
+	// HeaderOrder lists the keys of Headers in the order they were received
+	// when known, see EachHeader.
+	HeaderOrder []string `json:"-"`
+}
+
+// UnmarshalJSON parses the CloudMailin JSON format recording the order of
+// the headers in HeaderOrder.
+func (m *IncomingMail) UnmarshalJSON(b []byte) error {
+	type incomingMail IncomingMail
+	var mail struct {
+		incomingMail
+		Headers json.RawMessage `json:"headers"`
+	}
+
+	if err := json.Unmarshal(b, &mail); err != nil {
+		return err
+	}
+
+	*m = IncomingMail(mail.incomingMail)
+	if len(mail.Headers) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(mail.Headers, &m.Headers); err != nil {
+		return err
+	}
+	m.HeaderOrder = jsonKeys(mail.Headers)
+	return nil
+}
+
+// jsonKeys returns the keys of a JSON object in order, or nil if b isn't an
+// object.
+func jsonKeys(b []byte) (keys []string) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return keys
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return keys
+		}
+		keys = append(keys, token.(string))
+	}
+	return keys
 }
 
 // ParseIncoming parses an IO reader and emits an IncomingMail.
@@ -71,19 +125,38 @@ type IncomingMailHeader []string
 
 // UnmarshalJSON takes the CloudMailin JSON format and handles the parsing
 // into the struct. It ensures that every response is an array of strings.
+//
+// Numbers and booleans within an array are converted to strings, nulls are
+// skipped and nested values are kept as compact JSON. A null header is empty
+// and any other value that isn't a string or array is an error.
 func (i *IncomingMailHeader) UnmarshalJSON(b []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
 	var x interface{}
-	if err := json.Unmarshal(b, &x); err != nil {
+	if err := decoder.Decode(&x); err != nil {
 		return err
 	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid header: unexpected data after value")
+	}
 
 	switch v := x.(type) {
+	case nil:
+		*i = nil
 	case string:
 		*i = IncomingMailHeader([]string{v})
 	case []interface{}:
-		items := make([]string, 0)
+		items := make([]string, 0, len(v))
 		for _, v2 := range v {
-			items = append(items, v2.(string))
+			if v2 == nil {
+				continue
+			}
+			item, err := headerString(v2)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
 		}
 		*i = items
 	default:
@@ -93,11 +166,44 @@ func (i *IncomingMailHeader) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// headerString converts a decoded JSON value within a header array to a
+// string.
+func headerString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
 // Find will return a header by it's name
 // Email headers are ordered bottom up so they will be top
 // first in this array.
+//
+// The name can be given as CloudMailin's key (message_id) or any spelling of
+// the header name (Message-ID, message-id).
 func (i IncomingMailHeaders) Find(key string) IncomingMailHeader {
-	return i[key]
+	if header, ok := i[key]; ok {
+		return header
+	}
+
+	normalized := headerKey(key)
+	if header, ok := i[normalized]; ok {
+		return header
+	}
+
+	for name, header := range i {
+		if headerKey(name) == normalized {
+			return header
+		}
+	}
+	return nil
 }
 
 // First will return the first entry for a header by it's name.
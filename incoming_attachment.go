@@ -0,0 +1,170 @@
+package cloudmailin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrAttachmentSize is returned when the content of an attachment doesn't
+// match its Size.
+var ErrAttachmentSize = errors.New("attachment content does not match its size")
+
+// fetchClient returns httpClient, or http.DefaultClient if it is nil.
+func fetchClient(httpClient *http.Client) *http.Client {
+	if httpClient != nil {
+		return httpClient
+	}
+	return http.DefaultClient
+}
+
+// Open returns a reader for the decoded attachment content. If the content
+// was not included in the email the attachment is downloaded from the URL
+// using http.DefaultClient, see OpenWith. The caller must close the reader.
+//
+// When the Size is known, reading returns ErrAttachmentSize if the content
+// is a different size.
+func (a IncomingMailAttachment) Open() (io.ReadCloser, error) {
+	return a.OpenContext(context.Background())
+}
+
+// OpenContext opens the attachment in the same way as Open using ctx for
+// any download.
+func (a IncomingMailAttachment) OpenContext(ctx context.Context) (io.ReadCloser, error) {
+	return a.OpenWith(ctx, nil)
+}
+
+// OpenWith opens the attachment in the same way as OpenContext downloading
+// it with httpClient, when nil http.DefaultClient is used.
+func (a IncomingMailAttachment) OpenWith(ctx context.Context, httpClient *http.Client) (
+	io.ReadCloser, error) {
+
+	var body io.ReadCloser
+
+	if a.Content == "" && a.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, "GET", a.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := fetchClient(httpClient).Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, fmt.Errorf("could not fetch attachment (%d)", res.StatusCode)
+		}
+		body = res.Body
+	} else {
+		body = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding,
+			strings.NewReader(a.Content)))
+	}
+
+	if a.Size == 0 {
+		return body, nil
+	}
+	return &sizeCheckReader{ReadCloser: body, remaining: int64(a.Size)}, nil
+}
+
+// Bytes returns the decoded attachment content, see Open.
+func (a IncomingMailAttachment) Bytes() ([]byte, error) {
+	return a.BytesWith(context.Background(), nil)
+}
+
+// BytesWith returns the decoded attachment content in the same way as Bytes
+// using ctx and httpClient for any download, see OpenWith.
+func (a IncomingMailAttachment) BytesWith(ctx context.Context, httpClient *http.Client) (
+	[]byte, error) {
+
+	r, err := a.OpenWith(ctx, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// SaveTo writes the attachment content to a file in dir named using
+// SafeFileName, replacing any existing file. It returns the path of the
+// file written.
+func (a IncomingMailAttachment) SaveTo(dir string) (path string, err error) {
+	return a.SaveToWith(context.Background(), dir, nil)
+}
+
+// SaveToWith writes the attachment content to a file in dir in the same way
+// as SaveTo using ctx and httpClient for any download, see OpenWith.
+func (a IncomingMailAttachment) SaveToWith(ctx context.Context, dir string, httpClient *http.Client) (
+	path string, err error) {
+
+	r, err := a.OpenWith(ctx, httpClient)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	path = filepath.Join(dir, a.SafeFileName())
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return
+}
+
+// SafeFileName returns the FileName reduced to a single path element so
+// that it cannot be used to write outside of a directory. Directories,
+// control characters and leading dots are removed and "attachment" is
+// returned if nothing usable remains.
+func (a IncomingMailAttachment) SafeFileName() string {
+	name := strings.Replace(a.FileName, "\\", "/", -1)
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	if name == "" {
+		return "attachment"
+	}
+	return name
+}
+
+// sizeCheckReader returns ErrAttachmentSize if the underlying reader
+// doesn't contain exactly the expected number of bytes.
+type sizeCheckReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *sizeCheckReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.remaining -= int64(n)
+
+	if r.remaining < 0 || (err == io.EOF && r.remaining != 0) {
+		return n, ErrAttachmentSize
+	}
+	return n, err
+}
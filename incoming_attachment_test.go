@@ -0,0 +1,150 @@
+package cloudmailin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncomingMailAttachment_Bytes(t *testing.T) {
+	pixel, _ := base64.StdEncoding.DecodeString(pixelBase64)
+
+	t.Run("Inline content", func(t *testing.T) {
+		att := IncomingMailAttachment{Content: pixelBase64, Size: 70}
+		content, err := att.Bytes()
+		if err != nil || string(content) != string(pixel) {
+			t.Errorf("Expected decoded content got {%v}, {%v}", content, err)
+		}
+	})
+
+	t.Run("Unknown size", func(t *testing.T) {
+		att := IncomingMailAttachment{Content: pixelBase64}
+		if _, err := att.Bytes(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Size mismatch", func(t *testing.T) {
+		for _, size := range []uint64{69, 71} {
+			att := IncomingMailAttachment{Content: pixelBase64, Size: size}
+			if _, err := att.Bytes(); !errors.Is(err, ErrAttachmentSize) {
+				t.Errorf("Expected ErrAttachmentSize for %d got {%v}", size, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Base64", func(t *testing.T) {
+		att := IncomingMailAttachment{Content: "not base64!"}
+		if _, err := att.Bytes(); err == nil {
+			t.Error("Expected error but was nil")
+		}
+	})
+
+	t.Run("Remote URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/pixel.png" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(pixel)
+		}))
+		defer server.Close()
+
+		requests := 0
+		client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return http.DefaultTransport.RoundTrip(r)
+		})}
+
+		att := IncomingMailAttachment{URL: server.URL + "/pixel.png", Size: 70}
+		r, err := att.OpenWith(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil || string(content) != string(pixel) || requests != 1 {
+			t.Errorf("Expected downloaded content got {%v}, %d", err, requests)
+		}
+
+		content, err = att.BytesWith(context.Background(), client)
+		if err != nil || string(content) != string(pixel) || requests != 2 {
+			t.Errorf("Expected downloaded bytes got {%v}, %d", err, requests)
+		}
+
+		att.FileName = "pixel.png"
+		path, err := att.SaveToWith(context.Background(), t.TempDir(), client)
+		if saved, _ := ioutil.ReadFile(path); err != nil || string(saved) != string(pixel) || requests != 3 {
+			t.Errorf("Expected downloaded file got {%v}, %d", err, requests)
+		}
+
+		att.URL = server.URL + "/missing.png"
+		if _, err := att.Bytes(); err == nil {
+			t.Error("Expected error for missing attachment")
+		}
+	})
+}
+
+func TestIncomingMailAttachment_SafeFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"pixel.png", "pixel.png"},
+		{"../../etc/passwd", "passwd"},
+		{"..\\..\\windows\\system.ini", "system.ini"},
+		{"/absolute/path.txt", "path.txt"},
+		{"..", "attachment"},
+		{"", "attachment"},
+		{".hidden", "hidden"},
+		{"C:evil\x00.txt", "Cevil.txt"},
+		{"report/", "attachment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IncomingMailAttachment{FileName: tt.name}.SafeFileName()
+			if got != tt.expected {
+				t.Errorf("Expected {%s} got {%s}", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIncomingMailAttachment_SaveTo(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Valid", func(t *testing.T) {
+		att := IncomingMailAttachment{Content: pixelBase64, FileName: "../pixel.png", Size: 70}
+		path, err := att.SaveTo(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if path != filepath.Join(dir, "pixel.png") {
+			t.Errorf("Expected file inside dir got {%s}", path)
+		}
+
+		content, _ := ioutil.ReadFile(path)
+		if base64.StdEncoding.EncodeToString(content) != pixelBase64 {
+			t.Errorf("Unexpected file content {%v}", content)
+		}
+	})
+
+	t.Run("Size mismatch", func(t *testing.T) {
+		att := IncomingMailAttachment{Content: pixelBase64, FileName: "bad.png", Size: 10}
+		if _, err := att.SaveTo(dir); !errors.Is(err, ErrAttachmentSize) {
+			t.Errorf("Expected ErrAttachmentSize got {%v}", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "bad.png")); !os.IsNotExist(err) {
+			t.Error("Expected partial file to be removed")
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package cloudmailin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+const (
+	// DefaultIncomingSecretHeader is the header checked for a shared secret
+	// when IncomingAuth.SecretHeader is not set.
+	DefaultIncomingSecretHeader = "X-CloudMailin-Secret"
+
+	// DefaultIncomingSecretParam is the query parameter checked for a shared
+	// secret when IncomingAuth.SecretParam is not set.
+	DefaultIncomingSecretParam = "secret"
+)
+
+// IncomingCredentials is a username and password accepted using HTTP basic
+// authentication.
+type IncomingCredentials struct {
+	Username string
+	Password string
+}
+
+// IncomingAuth protects an incoming webhook so that only CloudMailin can
+// post to it. Requests are accepted if they contain basic authentication
+// matching any of the Credentials, or a shared secret matching any of the
+// Secrets in either the SecretHeader or the SecretParam query parameter.
+// Listing more than one value allows credentials to be rotated without
+// rejecting email.
+//
+// Requests without credentials receive 401 Unauthorized and requests with
+// incorrect credentials receive 403 Forbidden. All comparisons take
+// constant time.
+type IncomingAuth struct {
+	Credentials []IncomingCredentials
+	Secrets     []string
+
+	// SecretHeader defaults to DefaultIncomingSecretHeader.
+	SecretHeader string
+
+	// SecretParam defaults to DefaultIncomingSecretParam.
+	SecretParam string
+
+	// Realm is sent in the WWW-Authenticate header, it defaults to
+	// CloudMailin.
+	Realm string
+}
+
+// Wrap returns an http.Handler that authenticates requests before passing
+// them to next, typically an IncomingHandler.
+func (a IncomingAuth) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(a.Credentials) == 0 && len(a.Secrets) == 0 {
+			// Fail closed rather than accepting every request.
+			http.Error(w, "incoming authentication is not configured",
+				http.StatusInternalServerError)
+			return
+		}
+
+		presented, ok := a.authenticate(r)
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if presented {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		if len(a.Credentials) > 0 {
+			realm := a.Realm
+			if realm == "" {
+				realm = "CloudMailin"
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// authenticate reports whether the request presented any credentials and
+// whether they were valid.
+func (a IncomingAuth) authenticate(r *http.Request) (presented bool, ok bool) {
+	if username, password, found := r.BasicAuth(); found && len(a.Credentials) > 0 {
+		presented = true
+		match := 0
+		for _, c := range a.Credentials {
+			match |= secureCompare(username, c.Username) & secureCompare(password, c.Password)
+		}
+		if match == 1 {
+			return true, true
+		}
+	}
+
+	if len(a.Secrets) > 0 {
+		header := a.SecretHeader
+		if header == "" {
+			header = DefaultIncomingSecretHeader
+		}
+		param := a.SecretParam
+		if param == "" {
+			param = DefaultIncomingSecretParam
+		}
+
+		secret := r.Header.Get(header)
+		if secret == "" {
+			secret = r.URL.Query().Get(param)
+		}
+
+		if secret != "" {
+			presented = true
+			match := 0
+			for _, s := range a.Secrets {
+				match |= secureCompare(secret, s)
+			}
+			if match == 1 {
+				return true, true
+			}
+		}
+	}
+
+	return presented, false
+}
+
+// secureCompare returns 1 if a and b are equal and 0 otherwise in constant
+// time. The values are hashed first so the time taken doesn't reveal the
+// length of b.
+func secureCompare(a string, b string) int {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:])
+}
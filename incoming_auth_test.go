@@ -0,0 +1,87 @@
+package cloudmailin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIncomingAuth(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	auth := IncomingAuth{
+		Credentials: []IncomingCredentials{
+			{Username: "cloudmailin", Password: "old-password"},
+			{Username: "cloudmailin", Password: "new-password"},
+		},
+		Secrets: []string{"old-secret", "new-secret"},
+	}
+	handler := auth.Wrap(next)
+
+	tests := []struct {
+		name   string
+		url    string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"No credentials", "/", func(r *http.Request) {}, 401},
+		{"Basic auth", "/", func(r *http.Request) { r.SetBasicAuth("cloudmailin", "old-password") }, 200},
+		{"Rotated basic auth", "/", func(r *http.Request) { r.SetBasicAuth("cloudmailin", "new-password") }, 200},
+		{"Wrong password", "/", func(r *http.Request) { r.SetBasicAuth("cloudmailin", "nope") }, 403},
+		{"Wrong username", "/", func(r *http.Request) { r.SetBasicAuth("other", "new-password") }, 403},
+		{"Secret header", "/", func(r *http.Request) { r.Header.Set("X-CloudMailin-Secret", "new-secret") }, 200},
+		{"Secret param", "/?secret=old-secret", func(r *http.Request) {}, 200},
+		{"Wrong secret", "/?secret=guess", func(r *http.Request) {}, 403},
+		{"Wrong basic, valid secret", "/?secret=new-secret", func(r *http.Request) { r.SetBasicAuth("a", "b") }, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			tt.setup(req)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d got %d", tt.status, w.Code)
+			}
+		})
+	}
+
+	t.Run("WWW-Authenticate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+		expected := `Basic realm="CloudMailin", charset="UTF-8"`
+		if w.Header().Get("WWW-Authenticate") != expected {
+			t.Errorf("Expected {%s} got {%s}", expected, w.Header().Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("Custom secret location", func(t *testing.T) {
+		handler := IncomingAuth{Secrets: []string{"s"}, SecretHeader: "X-Token", SecretParam: "t"}.Wrap(next)
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest("POST", "/?t=s", nil),
+			httptest.NewRequest("POST", "/", nil),
+		} {
+			if req.URL.RawQuery == "" {
+				req.Header.Set("X-Token", "s")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Errorf("Expected 200 got %d", w.Code)
+			}
+		}
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		IncomingAuth{}.Wrap(next).ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500 got %d", w.Code)
+		}
+	})
+}
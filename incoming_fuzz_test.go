@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package cloudmailin
+
+import (
+	"os"
+	"testing"
+)
+
+func FuzzParseIncoming(f *testing.F) {
+	data, err := os.ReadFile("test/fixtures/post.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(data)
+	f.Add([]byte(`{"headers": {"received": ["a", 1, null, true, {"b": []}]}}`))
+	f.Add([]byte(`{"headers": null}`))
+	f.Add([]byte(`{"headers": {"to": 1}}`))
+	f.Add([]byte(`{"headers": []}`))
+	f.Add([]byte(`{"headers": {"received": "from x ("}}`))
+	f.Add([]byte(`{"headers": {"received": ["from mail (abc", "by mx (a (b) c)"]}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		message, err := ParseIncomingBytes(data)
+		if err != nil {
+			return
+		}
+
+		// Exercise the header helpers on anything that parses.
+		message.Headers.Find("Message-ID")
+		message.Headers.Received()
+		message.Headers.Date()
+		message.Headers.FromAddress()
+		message.EachHeader(func(name, value string) bool { return true })
+	})
+}
@@ -0,0 +1,138 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxIncomingBodySize is the largest request body accepted by an
+// IncomingHandler unless MaxBodySize is set.
+const DefaultMaxIncomingBodySize int64 = 50 << 20
+
+// IncomingHandlerFunc processes an email received from CloudMailin. The
+// returned error determines the status code sent back to CloudMailin, see
+// IncomingHandler.
+type IncomingHandlerFunc func(ctx context.Context, mail *IncomingMail) error
+
+// IncomingError can be returned by an IncomingHandlerFunc to choose the
+// HTTP status code returned to CloudMailin. The message is sent as the
+// response body. A StatusCode outside of 400-599 returns 500 Internal Server
+// Error so that CloudMailin retries the delivery.
+type IncomingError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns the message and status code.
+func (e *IncomingError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// RejectIncoming returns an error that causes CloudMailin to reject the
+// email, bouncing it back to the sender with message.
+func RejectIncoming(message string) error {
+	return &IncomingError{StatusCode: http.StatusForbidden, Message: message}
+}
+
+// IncomingHandler is an http.Handler that parses email posted by
+// CloudMailin in the JSON, multipart or raw format, see ParseIncomingRequest,
+// and passes it to Handler. CloudMailin uses the status code of the response
+// to decide what happens to the email:
+//
+//   - nil errors return 200 OK and the email is accepted.
+//   - an *IncomingError returns its StatusCode, a 4xx code such as the one
+//     used by RejectIncoming bounces the email.
+//   - any other error returns 500 Internal Server Error and CloudMailin will
+//     retry the delivery later.
+//
+// Requests that cannot be parsed return 400 Bad Request, bodies larger than
+// MaxBodySize return 413 Request Entity Too Large and methods not in Methods
+// return 405 Method Not Allowed.
+type IncomingHandler struct {
+	Handler IncomingHandlerFunc
+
+	// MaxBodySize limits the size of the request body, zero uses
+	// DefaultMaxIncomingBodySize.
+	MaxBodySize int64
+
+	// Methods lists the allowed HTTP methods, when empty only POST is
+	// allowed.
+	Methods []string
+}
+
+// NewIncomingHandler returns an IncomingHandler calling handler with the
+// default settings.
+func NewIncomingHandler(handler IncomingHandlerFunc) *IncomingHandler {
+	return &IncomingHandler{Handler: handler}
+}
+
+// ServeHTTP parses the request and calls the Handler.
+func (h *IncomingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods := h.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost}
+	}
+	if !containsFold(methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxBodySize := h.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxIncomingBodySize
+	}
+
+	if r.ContentLength > maxBodySize {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+			http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := &io.LimitedReader{R: r.Body, N: maxBodySize + 1}
+	limited := *r
+	limited.Body = ioutil.NopCloser(body)
+	mail, err := ParseIncomingRequest(&limited)
+	if body.N <= 0 {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge),
+			http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "could not parse email: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Handler(r.Context(), &mail); err != nil {
+		var incomingErr *IncomingError
+		if errors.As(err, &incomingErr) {
+			status := incomingErr.StatusCode
+			if status < 400 || status > 599 {
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, incomingErr.Message, status)
+			return
+		}
+
+		http.Error(w, http.StatusText(http.StatusInternalServerError),
+			http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// containsFold reports whether list contains s ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIncomingHandler(t *testing.T) {
+	fixture, _ := os.ReadFile("test/fixtures/post.json")
+
+	var received *IncomingMail
+	handler := NewIncomingHandler(func(ctx context.Context, mail *IncomingMail) error {
+		received = mail
+		switch mail.Envelope.To {
+		case "reject@cloudmailin.net":
+			return RejectIncoming("no such user")
+		case "fail@cloudmailin.net":
+			return errors.New("database unavailable")
+		case "custom@cloudmailin.net":
+			return &IncomingError{StatusCode: http.StatusServiceUnavailable, Message: "later"}
+		case "zero@cloudmailin.net":
+			return &IncomingError{Message: "no status"}
+		case "success@cloudmailin.net":
+			return &IncomingError{StatusCode: http.StatusOK, Message: "not an error"}
+		}
+		return nil
+	})
+
+	withTo := func(to string) string {
+		return strings.Replace(string(fixture), `"to": "postman@cloudmailin.net"`,
+			`"to": "`+to+`"`, -1)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		output string
+	}{
+		{"Accepted", "POST", string(fixture), 200, ""},
+		{"Rejected", "POST", withTo("reject@cloudmailin.net"), 403, "no such user\n"},
+		{"Error", "POST", withTo("fail@cloudmailin.net"), 500, "Internal Server Error\n"},
+		{"Custom status", "POST", withTo("custom@cloudmailin.net"), 503, "later\n"},
+		{"Missing status", "POST", withTo("zero@cloudmailin.net"), 500, "no status\n"},
+		{"Invalid status", "POST", withTo("success@cloudmailin.net"), 500, "not an error\n"},
+		{"Invalid JSON", "POST", "{ invalid", 400, ""},
+		{"Method", "GET", "", 405, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d got %d {%s}", tt.status, w.Code, w.Body)
+			}
+
+			if tt.output != "" && w.Body.String() != tt.output {
+				t.Errorf("Expected body {%s} got {%s}", tt.output, w.Body)
+			}
+		})
+	}
+
+	t.Run("Passes the parsed mail", func(t *testing.T) {
+		if received == nil || received.Headers.Subject() != "Test Email" {
+			t.Errorf("Expected parsed mail got {%v}", received)
+		}
+	})
+
+	t.Run("Allow header", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("PUT", "/", nil))
+		if w.Header().Get("Allow") != "POST" {
+			t.Errorf("Expected Allow header got {%s}", w.Header().Get("Allow"))
+		}
+	})
+}
+
+func TestIncomingHandler_MaxBodySize(t *testing.T) {
+	fixture, _ := os.ReadFile("test/fixtures/post.json")
+	handler := &IncomingHandler{
+		Handler:     func(ctx context.Context, mail *IncomingMail) error { return nil },
+		MaxBodySize: int64(len(fixture) - 1),
+		Methods:     []string{"POST", "PUT"},
+	}
+
+	t.Run("Content-Length", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(string(fixture))))
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 got %d", w.Code)
+		}
+	})
+
+	t.Run("Unknown length", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/", strings.NewReader(string(fixture)))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package cloudmailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// IncomingAttachmentFunc receives the content of each attachment while a
+// multipart request is parsed. The content must be consumed before the
+// function returns. Metadata sent after the file itself (such as the
+// content_id) is not available until parsing completes.
+type IncomingAttachmentFunc func(attachment *IncomingMailAttachment, content io.Reader) error
+
+// ParseIncomingRequest parses an email posted by CloudMailin in either the
+// JSON or the multipart format, choosing the parser from the Content-Type
+// of the request. Both versions of the raw format are also detected by their
+// message field.
+func ParseIncomingRequest(r *http.Request) (mail IncomingMail, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "", "application/json":
+		var data []byte
+		if data, err = ioutil.ReadAll(r.Body); err != nil {
+			return
+		}
+
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) == nil && fields["message"] != nil {
+			return parseIncomingRawJSON(bytes.NewReader(data))
+		}
+		return ParseIncomingBytes(data)
+	case "multipart/form-data":
+		return ParseIncomingMultipart(r)
+	}
+
+	err = fmt.Errorf("unsupported content type for incoming email: %q", mediaType)
+	return
+}
+
+// ParseIncomingMultipart parses an email posted by CloudMailin in the
+// multipart/form-data format into an IncomingMail. The request is read as a
+// stream but each attachment is Base64 encoded into its Content in memory,
+// use ParseIncomingMultipartFunc to stream attachments instead.
+//
+// If the request contains a message field (the raw format) the message is
+// parsed with ParseIncomingMessage.
+func ParseIncomingMultipart(r *http.Request) (mail IncomingMail, err error) {
+	return ParseIncomingMultipartFunc(r, nil)
+}
+
+// ParseIncomingMultipartFunc parses a multipart request in the same way as
+// ParseIncomingMultipart but passes the content of each attachment to fn
+// instead of storing it in the IncomingMailAttachment. This allows large
+// attachments to be written elsewhere without holding them in memory.
+func ParseIncomingMultipartFunc(r *http.Request, fn IncomingAttachmentFunc) (
+	mail IncomingMail, err error) {
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return
+	}
+
+	p := multipartParser{mail: &mail, attachments: map[string]int{}}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return mail, err
+		}
+
+		path := formKeyPath(part.FormName())
+		if len(path) == 1 && path[0] == "message" {
+			err = p.message(part)
+		} else if part.FileName() != "" && len(path) == 2 && path[0] == "attachments" {
+			err = p.attachment(path[1], part, fn)
+		} else {
+			var value []byte
+			if value, err = ioutil.ReadAll(part); err == nil {
+				err = p.field(path, string(value))
+			}
+		}
+		part.Close()
+
+		if err != nil {
+			return mail, err
+		}
+	}
+
+	return
+}
+
+// multipartParser assigns the parts of a multipart request to an
+// IncomingMail.
+type multipartParser struct {
+	mail *IncomingMail
+
+	// attachments maps the index used in the form to the position in
+	// mail.Attachments.
+	attachments map[string]int
+}
+
+// formKeyPath splits a form field name such as envelope[spf][result] into
+// its parts.
+func formKeyPath(name string) []string {
+	path := strings.Split(strings.TrimSuffix(name, "]"), "[")
+	for i := range path {
+		path[i] = strings.TrimSuffix(path[i], "]")
+	}
+	return path
+}
+
+// headerKey converts a header name to the key used in IncomingMailHeaders
+// such as message_id for Message-ID.
+func headerKey(name string) string {
+	return strings.ToLower(strings.Replace(name, "-", "_", -1))
+}
+
+// attachmentAt returns the attachment for the form index, creating it if
+// required.
+func (p *multipartParser) attachmentAt(index string) *IncomingMailAttachment {
+	i, ok := p.attachments[index]
+	if !ok {
+		i = len(p.mail.Attachments)
+		p.attachments[index] = i
+		p.mail.Attachments = append(p.mail.Attachments, IncomingMailAttachment{})
+	}
+	return &p.mail.Attachments[i]
+}
+
+// attachment reads a file part into the attachment at index.
+func (p *multipartParser) attachment(index string, part *multipart.Part,
+	fn IncomingAttachmentFunc) error {
+
+	att := p.attachmentAt(index)
+	att.FileName = part.FileName()
+	if contentType := part.Header.Get("Content-Type"); contentType != "" {
+		att.ContentType = contentType
+	}
+	if att.Disposition == "" {
+		att.Disposition = "attachment"
+	}
+
+	counter := &countingReader{r: part}
+	if fn != nil {
+		if err := fn(att, counter); err != nil {
+			return err
+		}
+		// Discard anything the function didn't read so the size is correct.
+		if _, err := io.Copy(ioutil.Discard, counter); err != nil {
+			return err
+		}
+	} else {
+		var content strings.Builder
+		encoder := base64.NewEncoder(base64.StdEncoding, &content)
+		if _, err := io.Copy(encoder, counter); err != nil {
+			return err
+		}
+		encoder.Close()
+		att.Content = content.String()
+	}
+
+	att = p.attachmentAt(index)
+	att.Size = uint64(counter.n)
+	return nil
+}
+
+// message parses the full message sent in the raw format, keeping the
+// envelope parsed from the other fields.
+func (p *multipartParser) message(part io.Reader) error {
+	message, err := ParseIncomingMessage(part)
+	if err != nil {
+		return err
+	}
+
+	message.Envelope = p.mail.Envelope
+	*p.mail = message
+	p.attachments = map[string]int{}
+	return nil
+}
+
+// field assigns a form value to the IncomingMail.
+func (p *multipartParser) field(path []string, value string) (err error) {
+	mail := p.mail
+
+	switch {
+	case len(path) == 1 && path[0] == "plain":
+		mail.Plain = value
+	case len(path) == 1 && path[0] == "html":
+		mail.HTML = value
+	case len(path) == 1 && path[0] == "reply_plain":
+		mail.ReplyPlain = value
+	case len(path) >= 2 && path[0] == "headers":
+		if mail.Headers == nil {
+			mail.Headers = IncomingMailHeaders{}
+		}
+		key := headerKey(path[1])
+		mail.Headers[key] = append(mail.Headers[key], value)
+	case len(path) >= 2 && path[0] == "envelope":
+		err = p.envelope(path[1:], value)
+	case len(path) >= 3 && path[0] == "attachments":
+		err = p.attachmentField(p.attachmentAt(path[1]), path[2:], value)
+	}
+
+	return
+}
+
+// envelope assigns an envelope[...] form value.
+func (p *multipartParser) envelope(path []string, value string) (err error) {
+	env := &p.mail.Envelope
+
+	switch strings.Join(path, ".") {
+	case "to":
+		env.To = value
+	case "from":
+		env.From = value
+	case "helo_domain":
+		env.HeloDomain = value
+	case "remote_ip":
+		env.RemoteIP = value
+	case "tls":
+		env.TLS, err = strconv.ParseBool(value)
+	case "tls_cipher":
+		env.TLSCipher = value
+	case "md5":
+		env.MD5 = value
+	case "store_url":
+		env.StoreURL = value
+	case "spf.result":
+		env.SPF.Result = value
+	case "spf.domain":
+		env.SPF.Domain = value
+	case "spamd.score":
+		var score float64
+		score, err = strconv.ParseFloat(value, 32)
+		env.SPAMD.Score = float32(score)
+	case "spamd.success":
+		env.SPAMD.Success, err = strconv.ParseBool(value)
+	case "spamd.description":
+		env.SPAMD.Description = value
+	default:
+		switch path[0] {
+		case "recipients":
+			env.Recipients = append(env.Recipients, value)
+		case "spamd":
+			if len(path) > 1 && path[1] == "symbols" {
+				env.SPAMD.Symbols = append(env.SPAMD.Symbols, value)
+			}
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("invalid envelope[%s] value: %w", strings.Join(path, "]["), err)
+	}
+	return
+}
+
+// attachmentField assigns an attachments[n][...] form value.
+func (p *multipartParser) attachmentField(att *IncomingMailAttachment, path []string,
+	value string) (err error) {
+
+	// Drop any array index such as scan][matches][0
+	if len(path) > 2 {
+		path = path[:2]
+	}
+
+	switch strings.Join(path, ".") {
+	case "content":
+		att.Content = value
+	case "file_name":
+		att.FileName = value
+	case "content_type":
+		att.ContentType = value
+	case "size":
+		att.Size, err = strconv.ParseUint(value, 10, 64)
+	case "disposition":
+		att.Disposition = value
+	case "content_id":
+		att.ContentID = value
+	case "url":
+		att.URL = value
+	case "scan.status":
+		att.Scan.Status = value
+	case "scan.id":
+		att.Scan.ID = value
+	case "scan.matches":
+		att.Scan.Matches = append(att.Scan.Matches, value)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("invalid attachment %s value: %w", strings.Join(path, "."), err)
+	}
+	return
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,221 @@
+package cloudmailin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const pixelBase64 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP0rdr1HwAFHwKCk87e6gAAAABJRU5ErkJggg=="
+
+// multipartFixture builds a multipart request equivalent to
+// test/fixtures/post.json.
+func multipartFixture(t *testing.T) (body *bytes.Buffer, contentType string) {
+	body = &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	fields := [][2]string{
+		{"headers[Received][0]", "by mail-qv1-f65.google.com with SMTP id p7so20148692qvl.4        for <postman@cloudmailin.net>; Wed, 08 Jul 2020 02:45:03 -0700"},
+		{"headers[Received][1]", "by localhost"},
+		{"headers[From]", "Steve Smith <test@example.com>"},
+		{"headers[To]", "postman@cloudmailin.net"},
+		{"headers[Subject]", "Test Email"},
+		{"headers[Message-ID]", "<CALazKR8Zr8Lsv+SUAeuaL-vrhWSCK36TRU8=7HjsenxwaP9ZbA@mail.gmail.com>"},
+		{"envelope[to]", "postman@cloudmailin.net"},
+		{"envelope[recipients][0]", "postman@cloudmailin.net"},
+		{"envelope[from]", "from+test@cloudmailin.net"},
+		{"envelope[helo_domain]", "cloudmailin.net"},
+		{"envelope[remote_ip]", "172.20.0.18"},
+		{"envelope[tls]", "true"},
+		{"envelope[tls_cipher]", "TLSv1.3"},
+		{"envelope[spf][result]", "fail"},
+		{"envelope[spf][domain]", "cloudmailin.net"},
+		{"envelope[store_url]", "http://example.s3.amazonaws.com/store/2020_10_22_09_55_18_ce5f9a939358ba89b80acd97f737e0db.eml"},
+		{"envelope[spamd][score]", "2.5"},
+		{"envelope[spamd][symbols][0]", "BAYES_50"},
+		{"envelope[spamd][symbols][1]", "HTML_MESSAGE"},
+		{"envelope[spamd][success]", "true"},
+		{"envelope[spamd][description]", "SpamAssassin score"},
+		{"plain", "Test Content\n\n> On 08 Jul 2020 at 10:00, example@cloudmailin.net wrote:\n> \n> Example message\n> Option: 2\n> \n\n"},
+		{"html", "<div dir=\"ltr\">Test Content<div><br></div></div>\n"},
+		{"reply_plain", "Test Content\n"},
+	}
+	for _, field := range fields {
+		w.WriteField(field[0], field[1])
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="attachments[0]"; filename="pixel.png"`)
+	header.Set("Content-Type", "image/png")
+	part, _ := w.CreatePart(header)
+	pixel, _ := base64.StdEncoding.DecodeString(pixelBase64)
+	part.Write(pixel)
+
+	w.WriteField("attachments[0][content_id]", "<f_kcd6ejvs1>")
+	w.WriteField("attachments[0][scan][status]", "ok")
+	w.WriteField("attachments[0][scan][matches][0]", "Example Match")
+	w.Close()
+
+	return body, w.FormDataContentType()
+}
+
+func TestParseIncomingMultipart(t *testing.T) {
+	body, contentType := multipartFixture(t)
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", contentType)
+
+	mail, err := ParseIncomingMultipart(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	expected, _ := ParseIncoming(data)
+
+	tests := []struct {
+		name     string
+		item     interface{}
+		expected interface{}
+	}{
+		{"Envelope", mail.Envelope, expected.Envelope},
+		{"Plain", mail.Plain, expected.Plain},
+		{"HTML", mail.HTML, expected.HTML},
+		{"ReplyPlain", mail.ReplyPlain, expected.ReplyPlain},
+		{"Received", mail.Headers.Find("received"), expected.Headers.Find("received")},
+		{"From", mail.Headers.From(), expected.Headers.From()},
+		{"MessageID", mail.Headers.MessageID(), expected.Headers.MessageID()},
+		{"Attachments", mail.Attachments, []IncomingMailAttachment{{
+			Content:     pixelBase64,
+			FileName:    "pixel.png",
+			ContentType: "image/png",
+			Size:        70,
+			Disposition: "attachment",
+			ContentID:   "<f_kcd6ejvs1>",
+			Scan:        IncomingMailAttachmentScan{Status: "ok", Matches: []string{"Example Match"}},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !cmp.Equal(tt.expected, tt.item) {
+				t.Errorf("Expected {%v} but was {%v}\n{%v}", tt.expected, tt.item,
+					cmp.Diff(tt.expected, tt.item))
+			}
+		})
+	}
+}
+
+func TestParseIncomingMultipartFunc(t *testing.T) {
+	body, contentType := multipartFixture(t)
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", contentType)
+
+	var streamed []byte
+	mail, err := ParseIncomingMultipartFunc(req,
+		func(att *IncomingMailAttachment, content io.Reader) (err error) {
+			streamed, err = ioutil.ReadAll(content)
+			return
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base64.StdEncoding.EncodeToString(streamed) != pixelBase64 {
+		t.Errorf("Expected attachment content to be streamed got {%v}", streamed)
+	}
+
+	if mail.Attachments[0].Content != "" || mail.Attachments[0].Size != 70 {
+		t.Errorf("Expected no stored content got {%v}", mail.Attachments[0])
+	}
+}
+
+func TestParseIncomingRequest(t *testing.T) {
+	fixture, _ := os.ReadFile("test/fixtures/post.json")
+
+	t.Run("JSON", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(fixture))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		mail, err := ParseIncomingRequest(req)
+		if err != nil || mail.Headers.Subject() != "Test Email" {
+			t.Errorf("Expected JSON to be parsed got {%v}", err)
+		}
+	})
+
+	t.Run("Multipart", func(t *testing.T) {
+		body, contentType := multipartFixture(t)
+		req := httptest.NewRequest("POST", "/", body)
+		req.Header.Set("Content-Type", contentType)
+		mail, err := ParseIncomingRequest(req)
+		if err != nil || mail.Headers.Subject() != "Test Email" {
+			t.Errorf("Expected multipart to be parsed got {%v}", err)
+		}
+	})
+
+	t.Run("Raw JSON", func(t *testing.T) {
+		message, _ := os.ReadFile("test/fixtures/message.eml")
+		body, _ := json.Marshal(map[string]interface{}{
+			"envelope": map[string]string{"to": "postman@cloudmailin.net"},
+			"message":  string(message),
+		})
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		mail, err := ParseIncomingRequest(req)
+		if err != nil || mail.Envelope.To != "postman@cloudmailin.net" ||
+			mail.Headers.Subject() != "Test Email ✓" {
+			t.Errorf("Expected raw JSON to be parsed got {%v}", err)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("a=b"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		_, err := ParseIncomingRequest(req)
+		if err == nil || !strings.Contains(err.Error(), "unsupported content type") {
+			t.Errorf("Expected error got {%v}", err)
+		}
+	})
+
+	t.Run("Invalid envelope value", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		w.WriteField("envelope[tls]", "maybe")
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		_, err := ParseIncomingRequest(req)
+		if err == nil || !strings.Contains(err.Error(), "envelope[tls]") {
+			t.Errorf("Expected error got {%v}", err)
+		}
+	})
+}
+
+func TestIncomingHandler_Multipart(t *testing.T) {
+	body, contentType := multipartFixture(t)
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", contentType)
+
+	var subject string
+	handler := NewIncomingHandler(func(ctx context.Context, mail *IncomingMail) error {
+		subject = mail.Headers.Subject()
+		return nil
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != 200 || subject != "Test Email" {
+		t.Errorf("Expected multipart to be handled got %d {%s}", w.Code, subject)
+	}
+}
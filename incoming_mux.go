@@ -0,0 +1,203 @@
+package cloudmailin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IncomingMux routes incoming email to a handler based on the envelope To
+// address, the address the email was delivered to. Its ServeIncoming method
+// is an IncomingHandlerFunc so it can be used with NewIncomingHandler.
+//
+// Patterns registered with Handle are matched without regard to case and
+// can be:
+//
+//   - an exact address such as support@example.com.
+//   - a domain such as @example.com, matching any address at that domain.
+//   - an address containing * wildcards such as *@example.com or
+//     support@*.example.com, a wildcard never matches the @.
+//   - an address containing named parameters such as
+//     reply+{token}@example.com, see IncomingParam.
+//
+// Regular expressions can be registered with HandleRegexp, named groups are
+// captured as parameters.
+//
+// Exact addresses take priority, otherwise the first matching pattern in the
+// order they were registered is used. Email matching no pattern is passed to
+// Default or rejected if Default is nil.
+type IncomingMux struct {
+	// Default handles email that doesn't match any pattern.
+	Default IncomingHandlerFunc
+
+	mu     sync.RWMutex
+	exact  map[string]IncomingHandlerFunc
+	routes []incomingRoute
+}
+
+type incomingRoute struct {
+	pattern string
+	re      *regexp.Regexp
+	handler IncomingHandlerFunc
+}
+
+// NewIncomingMux returns an empty IncomingMux.
+func NewIncomingMux() *IncomingMux {
+	return &IncomingMux{}
+}
+
+var incomingParamPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Handle registers handler for pattern. Handle panics if the pattern is
+// invalid or has already been registered.
+func (m *IncomingMux) Handle(pattern string, handler IncomingHandlerFunc) {
+	if handler == nil {
+		panic("cloudmailin: nil handler for " + pattern)
+	}
+
+	address := strings.TrimSpace(pattern)
+	if !strings.Contains(address, "@") {
+		panic("cloudmailin: invalid incoming pattern " + pattern)
+	}
+	if strings.HasPrefix(address, "@") {
+		address = "*" + address
+	}
+
+	if !strings.ContainsAny(address, "*{}") {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		address = strings.ToLower(address)
+		if _, ok := m.exact[address]; ok {
+			panic("cloudmailin: multiple registrations for " + pattern)
+		}
+		if m.exact == nil {
+			m.exact = make(map[string]IncomingHandlerFunc)
+		}
+		m.exact[address] = handler
+		return
+	}
+
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	rest := address
+	for rest != "" {
+		if loc := incomingParamPattern.FindStringSubmatchIndex(rest); loc != nil && loc[0] == 0 {
+			fmt.Fprintf(&expr, "(?P<%s>[^@]+)", rest[loc[2]:loc[3]])
+			rest = rest[loc[1]:]
+			continue
+		}
+
+		switch rest[0] {
+		case '*':
+			expr.WriteString("[^@]*")
+		case '{', '}':
+			panic("cloudmailin: invalid incoming pattern " + pattern)
+		default:
+			expr.WriteString(regexp.QuoteMeta(rest[:1]))
+		}
+		rest = rest[1:]
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		panic("cloudmailin: invalid incoming pattern " + pattern + ": " + err.Error())
+	}
+	m.handle(pattern, re, handler)
+}
+
+// HandleRegexp registers handler for addresses matching re. Named groups in
+// re are available with IncomingParam.
+func (m *IncomingMux) HandleRegexp(re *regexp.Regexp, handler IncomingHandlerFunc) {
+	if re == nil || handler == nil {
+		panic("cloudmailin: nil regexp or handler")
+	}
+	m.handle(re.String(), re, handler)
+}
+
+func (m *IncomingMux) handle(pattern string, re *regexp.Regexp, handler IncomingHandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, route := range m.routes {
+		if route.pattern == pattern {
+			panic("cloudmailin: multiple registrations for " + pattern)
+		}
+	}
+	m.routes = append(m.routes, incomingRoute{pattern: pattern, re: re, handler: handler})
+}
+
+// Handler returns the handler and parameters for address, or nil if no
+// pattern matches.
+func (m *IncomingMux) Handler(address string) (handler IncomingHandlerFunc, params map[string]string) {
+	address = strings.TrimSpace(address)
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "<"), ">")
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if handler, ok := m.exact[strings.ToLower(address)]; ok {
+		return handler, nil
+	}
+
+	for _, route := range m.routes {
+		match := route.re.FindStringSubmatch(address)
+		if match == nil {
+			continue
+		}
+
+		for i, name := range route.re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name] = match[i]
+		}
+		return route.handler, params
+	}
+
+	return nil, nil
+}
+
+// ServeIncoming passes mail to the handler matching its envelope To address.
+func (m *IncomingMux) ServeIncoming(ctx context.Context, mail *IncomingMail) error {
+	handler, params := m.Handler(mail.Envelope.To)
+	if handler == nil {
+		if m.Default == nil {
+			return RejectIncoming("unknown recipient")
+		}
+		handler = m.Default
+	}
+
+	if len(params) > 0 {
+		merged := make(map[string]string, len(params))
+		for name, value := range IncomingParams(ctx) {
+			merged[name] = value
+		}
+		for name, value := range params {
+			merged[name] = value
+		}
+		ctx = context.WithValue(ctx, incomingParamsKey{}, merged)
+	}
+
+	return handler(ctx, mail)
+}
+
+type incomingParamsKey struct{}
+
+// IncomingParams returns the parameters captured by the IncomingMux pattern
+// that matched the email, or nil if there are none.
+func IncomingParams(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(incomingParamsKey{}).(map[string]string)
+	return params
+}
+
+// IncomingParam returns a single parameter captured by the IncomingMux, such
+// as token for the pattern reply+{token}@example.com.
+func IncomingParam(ctx context.Context, name string) string {
+	return IncomingParams(ctx)[name]
+}
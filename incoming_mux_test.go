@@ -0,0 +1,152 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncomingMux(t *testing.T) {
+	var route string
+	var params map[string]string
+	handler := func(name string) IncomingHandlerFunc {
+		return func(ctx context.Context, mail *IncomingMail) error {
+			route = name
+			params = IncomingParams(ctx)
+			return nil
+		}
+	}
+
+	mux := NewIncomingMux()
+	mux.Handle("reply+{token}@example.com", handler("reply"))
+	mux.Handle("support@example.com", handler("support"))
+	mux.Handle("Invoices@Example.com", handler("invoices"))
+	mux.Handle("*@support.example.com", handler("wildcard"))
+	mux.Handle("{team}-alerts@*.example.com", handler("alerts"))
+	mux.HandleRegexp(regexp.MustCompile(`^order-(?P<id>\d+)@example\.com$`), handler("order"))
+	mux.Handle("@example.com", handler("domain"))
+
+	tests := []struct {
+		to     string
+		route  string
+		params map[string]string
+	}{
+		{"support@example.com", "support", nil},
+		{"<SUPPORT@example.COM>", "support", nil},
+		{"invoices@example.com", "invoices", nil},
+		{"reply+abc123@example.com", "reply", map[string]string{"token": "abc123"}},
+		{"Reply+ABC@EXAMPLE.COM", "reply", map[string]string{"token": "ABC"}},
+		{"anyone@support.example.com", "wildcard", nil},
+		{"ops-alerts@eu.example.com", "alerts", map[string]string{"team": "ops"}},
+		{"order-42@example.com", "order", map[string]string{"id": "42"}},
+		{"order-abc@example.com", "domain", nil},
+		{"reply+@example.com", "domain", nil},
+		{"anyone@example.com", "domain", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			route, params = "", nil
+			mail := &IncomingMail{Envelope: IncomingMailEnvelope{To: tt.to}}
+			if err := mux.ServeIncoming(context.Background(), mail); err != nil {
+				t.Fatalf("Expected error to be nil but was {%v}", err)
+			}
+			if route != tt.route {
+				t.Errorf("Expected {%v} got {%v}", tt.route, route)
+			}
+			if !cmp.Equal(tt.params, params) {
+				t.Errorf("Expected {%v} got {%v}", tt.params, params)
+			}
+		})
+	}
+
+	t.Run("Unknown Recipient", func(t *testing.T) {
+		mail := &IncomingMail{Envelope: IncomingMailEnvelope{To: "user@example.net"}}
+		err := mux.ServeIncoming(context.Background(), mail)
+
+		var incomingErr *IncomingError
+		if !errors.As(err, &incomingErr) || incomingErr.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected rejection got {%v}", err)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		mux.Default = handler("default")
+		defer func() { mux.Default = nil }()
+
+		mail := &IncomingMail{Envelope: IncomingMailEnvelope{To: "user@example.net"}}
+		if err := mux.ServeIncoming(context.Background(), mail); err != nil || route != "default" {
+			t.Errorf("Expected {default} got {%v} (%v)", route, err)
+		}
+	})
+}
+
+func TestIncomingMux_Handle_Invalid(t *testing.T) {
+	noop := func(ctx context.Context, mail *IncomingMail) error { return nil }
+
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"No At", "support"},
+		{"Unclosed Param", "reply+{token@example.com"},
+		{"Invalid Param", "reply+{1}@example.com"},
+		{"Duplicate Exact", "Support@example.com"},
+		{"Duplicate Pattern", "*@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := NewIncomingMux()
+			mux.Handle("support@example.com", noop)
+			mux.Handle("*@example.com", noop)
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for {%v}", tt.pattern)
+				}
+			}()
+			mux.Handle(tt.pattern, noop)
+		})
+	}
+}
+
+func TestIncomingMux_IncomingHandler(t *testing.T) {
+	var token string
+	mux := NewIncomingMux()
+	mux.Handle("reply+{token}@cloudmailin.net", func(ctx context.Context, mail *IncomingMail) error {
+		token = IncomingParam(ctx, "token")
+		return nil
+	})
+
+	data, _ := os.ReadFile("test/fixtures/post.json")
+	body := strings.Replace(string(data), `"to": "postman@cloudmailin.net"`,
+		`"to": "reply+xyz@cloudmailin.net"`, -1)
+
+	handler := NewIncomingHandler(mux.ServeIncoming)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || token != "xyz" {
+		t.Errorf("Expected {200 xyz} got {%v %v}", w.Code, token)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/", strings.NewReader(string(data)))
+	r.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected {403} got {%v}", w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package cloudmailin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultMaxOriginalSize is the largest stored message that FetchOriginal
+// will download when FetchOriginalOptions.MaxSize isn't set.
+const DefaultMaxOriginalSize int64 = 50 << 20
+
+// FetchOriginalOptions controls how FetchOriginal downloads a message.
+type FetchOriginalOptions struct {
+	// HTTPClient downloads the message, when nil http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// MaxSize is the largest message that will be downloaded, zero uses
+	// DefaultMaxOriginalSize.
+	MaxSize int64
+}
+
+var (
+	// ErrNoStoreURL is returned by FetchOriginal when the envelope doesn't
+	// contain a StoreURL.
+	ErrNoStoreURL = errors.New("envelope has no store URL")
+
+	// ErrOriginalTooLarge is returned by FetchOriginal when the stored
+	// message is larger than the MaxSize.
+	ErrOriginalTooLarge = errors.New("original message is too large")
+)
+
+// FetchOriginal downloads the original message from the StoreURL. The
+// message is returned exactly as it was received, use ParseIncomingMessage
+// or FetchOriginalMIME to decode it.
+func (e IncomingMailEnvelope) FetchOriginal(ctx context.Context, opts FetchOriginalOptions) (
+	[]byte, error) {
+
+	if e.StoreURL == "" {
+		return nil, ErrNoStoreURL
+	}
+
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxOriginalSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", e.StoreURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := fetchClient(opts.HTTPClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch original message (%d)", res.StatusCode)
+	}
+
+	if res.ContentLength > maxSize {
+		return nil, ErrOriginalTooLarge
+	}
+
+	message, err := ioutil.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(message)) > maxSize {
+		return nil, ErrOriginalTooLarge
+	}
+
+	return message, nil
+}
+
+// FetchOriginalMIME downloads the original message in the same way as
+// FetchOriginal and parses it into a tree of MIMEParts.
+func (e IncomingMailEnvelope) FetchOriginalMIME(ctx context.Context, opts FetchOriginalOptions) (
+	*MIMEPart, error) {
+
+	message, err := e.FetchOriginal(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMIME(bytes.NewReader(message))
+}
@@ -0,0 +1,85 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestIncomingMailEnvelope_FetchOriginal(t *testing.T) {
+	message, _ := os.ReadFile("test/fixtures/message.eml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/store/message.eml":
+			w.Write(message)
+		case "/store/chunked.eml":
+			w.(http.Flusher).Flush()
+			w.Write(message)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("Raw", func(t *testing.T) {
+		env := IncomingMailEnvelope{StoreURL: server.URL + "/store/message.eml"}
+		raw, err := env.FetchOriginal(ctx, FetchOriginalOptions{})
+		if err != nil || string(raw) != string(message) {
+			t.Errorf("Expected original message got {%v}", err)
+		}
+	})
+
+	t.Run("HTTP client", func(t *testing.T) {
+		requests := 0
+		client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			return http.DefaultTransport.RoundTrip(r)
+		})}
+
+		env := IncomingMailEnvelope{StoreURL: server.URL + "/store/message.eml"}
+		raw, err := env.FetchOriginal(ctx, FetchOriginalOptions{HTTPClient: client})
+		if err != nil || string(raw) != string(message) || requests != 1 {
+			t.Errorf("Expected original message using client got {%v}, %d", err, requests)
+		}
+	})
+
+	t.Run("MIME", func(t *testing.T) {
+		env := IncomingMailEnvelope{StoreURL: server.URL + "/store/message.eml"}
+		root, err := env.FetchOriginalMIME(ctx, FetchOriginalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if root.MediaType != "multipart/mixed" || len(root.Parts) != 2 {
+			t.Errorf("Unexpected MIME tree {%v}", root.MediaType)
+		}
+	})
+
+	t.Run("No store URL", func(t *testing.T) {
+		if _, err := (IncomingMailEnvelope{}).FetchOriginal(ctx, FetchOriginalOptions{}); !errors.Is(err, ErrNoStoreURL) {
+			t.Errorf("Expected ErrNoStoreURL got {%v}", err)
+		}
+	})
+
+	t.Run("Error status", func(t *testing.T) {
+		env := IncomingMailEnvelope{StoreURL: server.URL + "/store/expired.eml"}
+		if _, err := env.FetchOriginal(ctx, FetchOriginalOptions{}); err == nil {
+			t.Error("Expected error but was nil")
+		}
+	})
+
+	t.Run("Too large", func(t *testing.T) {
+		opts := FetchOriginalOptions{MaxSize: int64(len(message) - 1)}
+		for _, path := range []string{"/store/message.eml", "/store/chunked.eml"} {
+			env := IncomingMailEnvelope{StoreURL: server.URL + path}
+			if _, err := env.FetchOriginal(ctx, opts); !errors.Is(err, ErrOriginalTooLarge) {
+				t.Errorf("Expected ErrOriginalTooLarge for %s got {%v}", path, err)
+			}
+		}
+	})
+}
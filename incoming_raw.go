@@ -0,0 +1,98 @@
+package cloudmailin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ParseIncomingRaw parses an email posted by CloudMailin in the raw format.
+// The envelope is read from the envelope fields and the full message is
+// decoded into the headers, plain and HTML parts and attachments. Both the
+// multipart and JSON versions of the raw format are supported.
+func ParseIncomingRaw(r *http.Request) (mail IncomingMail, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return ParseIncomingMultipart(r)
+	}
+
+	return parseIncomingRawJSON(r.Body)
+}
+
+// parseIncomingRawJSON parses the JSON version of the raw format.
+func parseIncomingRawJSON(data io.Reader) (mail IncomingMail, err error) {
+	var raw struct {
+		Envelope IncomingMailEnvelope `json:"envelope"`
+		Message  string               `json:"message"`
+	}
+	if err = json.NewDecoder(data).Decode(&raw); err != nil {
+		return
+	}
+
+	mail, err = ParseIncomingMessage(strings.NewReader(raw.Message))
+	mail.Envelope = raw.Envelope
+	return
+}
+
+// ParseIncomingMessage decodes a complete RFC 822 email message into an
+// IncomingMail. The Envelope is left empty as it isn't part of the message.
+//
+// The first text/plain and text/html parts that are not attachments become
+// Plain and HTML, every other part is added to the Attachments.
+func ParseIncomingMessage(r io.Reader) (mail IncomingMail, err error) {
+	root, err := ParseMIME(r)
+	if err != nil {
+		return
+	}
+
+	mail.Headers = headersFromMIME(root)
+	root.Walk(func(part *MIMEPart) {
+		if len(part.Parts) > 0 || strings.HasPrefix(part.MediaType, "multipart/") {
+			return
+		}
+
+		disposition := part.Disposition()
+		fileName := part.FileName()
+		if disposition != "attachment" && fileName == "" {
+			switch {
+			case part.MediaType == "text/plain" && mail.Plain == "":
+				mail.Plain = part.Text()
+				return
+			case part.MediaType == "text/html" && mail.HTML == "":
+				mail.HTML = part.Text()
+				return
+			}
+		}
+
+		if disposition == "" {
+			disposition = "attachment"
+		}
+
+		mail.Attachments = append(mail.Attachments, IncomingMailAttachment{
+			Content:     base64.StdEncoding.EncodeToString(part.Body),
+			FileName:    fileName,
+			ContentType: part.MediaType,
+			Size:        uint64(len(part.Body)),
+			Disposition: disposition,
+			ContentID:   part.Header.Get("Content-ID"),
+		})
+	})
+
+	return
+}
+
+// headersFromMIME converts the headers of a message to IncomingMailHeaders
+// decoding any RFC 2047 encoded-words.
+func headersFromMIME(part *MIMEPart) IncomingMailHeaders {
+	headers := IncomingMailHeaders{}
+	for name, values := range part.Header {
+		key := headerKey(name)
+		for _, value := range values {
+			headers[key] = append(headers[key], decodeHeader(value))
+		}
+	}
+	return headers
+}
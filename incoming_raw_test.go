@@ -0,0 +1,111 @@
+package cloudmailin
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseIncomingMessage(t *testing.T) {
+	data, _ := os.Open("test/fixtures/message.eml")
+	defer data.Close()
+
+	mail, err := ParseIncomingMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		item     interface{}
+		expected interface{}
+	}{
+		{"Subject", mail.Headers.Subject(), "Test Email ✓"},
+		{"From", mail.Headers.From(), "Steve Smith <test@example.com>"},
+		{"MessageID", mail.Headers.MessageID(),
+			"<CALazKR8Zr8Lsv+SUAeuaL-vrhWSCK36TRU8=7HjsenxwaP9ZbA@mail.gmail.com>"},
+		{"Received", mail.Headers.Find("received"), IncomingMailHeader{
+			"by mail-qv1-f65.google.com with SMTP id p7so20148692qvl.4 " +
+				"for <postman@cloudmailin.net>; Wed, 08 Jul 2020 02:45:03 -0700",
+			"by localhost",
+		}},
+		{"Plain", mail.Plain, "Test Content ✓\n\n> On 08 Jul 2020 at 10:00, " +
+			"example@cloudmailin.net wrote:\n> Example message\n"},
+		{"HTML", mail.HTML, "<div dir=\"ltr\">Test Content<div><br></div></div>\n"},
+		{"Attachments", mail.Attachments, []IncomingMailAttachment{{
+			Content:     pixelBase64,
+			FileName:    "pixel.png",
+			ContentType: "image/png",
+			Size:        70,
+			Disposition: "attachment",
+			ContentID:   "<f_kcd6ejvs1>",
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !cmp.Equal(tt.expected, tt.item) {
+				t.Errorf("Expected {%v} but was {%v}\n{%v}", tt.expected, tt.item,
+					cmp.Diff(tt.expected, tt.item))
+			}
+		})
+	}
+
+	t.Run("Invalid message", func(t *testing.T) {
+		_, err := ParseIncomingMessage(strings.NewReader("not a message"))
+		if err == nil {
+			t.Error("Expected error but was nil")
+		}
+	})
+}
+
+func TestParseIncomingRaw(t *testing.T) {
+	message, _ := os.ReadFile("test/fixtures/message.eml")
+
+	t.Run("Multipart", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		w.WriteField("envelope[to]", "postman@cloudmailin.net")
+		w.WriteField("message", string(message))
+		w.WriteField("envelope[from]", "from+test@cloudmailin.net")
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+
+		mail, err := ParseIncomingRaw(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mail.Envelope.To != "postman@cloudmailin.net" ||
+			mail.Envelope.From != "from+test@cloudmailin.net" ||
+			mail.Headers.Subject() != "Test Email ✓" || len(mail.Attachments) != 1 {
+			t.Errorf("Unexpected mail {%v}", mail)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"envelope": map[string]string{"to": "postman@cloudmailin.net"},
+			"message":  string(message),
+		})
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		mail, err := ParseIncomingRaw(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mail.Envelope.To != "postman@cloudmailin.net" || mail.HTML == "" {
+			t.Errorf("Unexpected mail {%v}", mail)
+		}
+	})
+}
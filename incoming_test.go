@@ -136,6 +136,51 @@ func TestIncomingMailHeader_UnmarshalJSON(t *testing.T) {
 			t.Error("Expected error but was nil", header)
 		}
 	})
+
+	t.Run("Trailing Data", func(t *testing.T) {
+		header := IncomingMailHeader{}
+		if err := header.UnmarshalJSON([]byte(`"a" "b"`)); err == nil {
+			t.Error("Expected error but was nil", header)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected IncomingMailHeader
+	}{
+		{"String", `"value"`, IncomingMailHeader{"value"}},
+		{"Null", `null`, nil},
+		{"Numbers", `[1, 2.50, -3e2]`, IncomingMailHeader{"1", "2.50", "-3e2"}},
+		{"Bools", `[true, false]`, IncomingMailHeader{"true", "false"}},
+		{"Nulls", `["a", null, "b"]`, IncomingMailHeader{"a", "b"}},
+		{"Nested", `[{"b": 1, "a": [1, null]}, ["x"]]`,
+			IncomingMailHeader{`{"a":[1,null],"b":1}`, `["x"]`}},
+		{"Empty", `[]`, IncomingMailHeader{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := IncomingMailHeader{"previous"}
+			if err := header.UnmarshalJSON([]byte(tt.data)); err != nil {
+				t.Fatalf("Expected error to be nil but was {%v}", err)
+			}
+			if !cmp.Equal(tt.expected, header) {
+				t.Errorf("Expected {%v} got {%v}", tt.expected, header)
+			}
+		})
+	}
+
+	t.Run("In Message", func(t *testing.T) {
+		data := `{"headers": {"x_count": [1, null], "x_flag": null}}`
+		message, err := ParseIncomingBytes([]byte(data))
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was {%v}", err)
+		}
+		if value := message.Headers.First("x_count"); value != "1" {
+			t.Errorf("Expected {1} got {%v}", value)
+		}
+	})
 }
 
 func TestIncomingMailHeaders_Find(t *testing.T) {
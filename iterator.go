@@ -0,0 +1,168 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// NextPageTokenHeader is the response header used by list endpoints to
+// return the token for the next page when a Link header is not used.
+const NextPageTokenHeader = "X-Next-Page-Token"
+
+// errEmptyPage is returned when a page contains no items but the response
+// still advertises a further page.
+var errEmptyPage = errors.New("list page was empty but a further page was advertised")
+
+// ListOptions contains the paging options shared by list endpoints.
+type ListOptions struct {
+	// PerPage is the number of items requested in each page.
+	PerPage int
+
+	// PageToken requests a specific page.
+	PageToken string
+}
+
+// Pager fetches successive pages from a list endpoint that returns a JSON
+// array. The following page is found from the Link header (rel="next") or
+// the X-Next-Page-Token header. The typed iterators such as MessageIterator
+// are built on a Pager, it can also be used directly for other endpoints:
+//
+//	pager := client.NewPager(ctx, "/addresses", nil, RequestTypeAccount)
+//	var page []Address
+//	for pager.NextPage(&page) {
+//		...
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+//
+// Any error fetching or decoding a page, including a truncated response,
+// stops the Pager and is returned by Err. Items from earlier pages remain
+// valid.
+type Pager struct {
+	client Client
+	ctx    context.Context
+	path   string
+	query  url.Values
+	kind   RequestType
+	res    *http.Response
+	done   bool
+	err    error
+}
+
+// NewPager returns a Pager for the list endpoint at path. The query is sent
+// with the first request and updated for each following page.
+func (client Client) NewPager(ctx context.Context, path string, query url.Values,
+	kind RequestType) *Pager {
+
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = append([]string(nil), values...)
+	}
+
+	return &Pager{client: client, ctx: ctx, path: path, query: q, kind: kind}
+}
+
+// newListPager returns a Pager for path applying opts.
+func (client Client) newListPager(ctx context.Context, path string, query url.Values,
+	opts ListOptions, kind RequestType) *Pager {
+
+	pager := client.NewPager(ctx, path, query, kind)
+	if opts.PerPage > 0 {
+		pager.query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	if opts.PageToken != "" {
+		pager.query.Set("page_token", opts.PageToken)
+	}
+	return pager
+}
+
+// NextPage fetches the next page decoding it into out, which must be a
+// pointer to a slice. It returns false when there are no more pages, the
+// Pager has been stopped or an error occurred.
+func (p *Pager) NextPage(out interface{}) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	if p.err = p.ctx.Err(); p.err != nil {
+		return false
+	}
+
+	slice := reflect.ValueOf(out)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		p.err = errors.New("pager: out must be a pointer to a slice")
+		return false
+	}
+	slice.Elem().SetLen(0)
+
+	req, err := p.client.NewRequest(p.ctx, "GET", p.path, p.query, nil, p.kind)
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	res, err := p.client.DoRequest(req, out)
+	p.res = res
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	p.done = true
+	if next := linkNext(res.Header.Get("Link")); next != "" {
+		if u, err := url.Parse(next); err == nil {
+			p.query, p.done = u.Query(), false
+		}
+	} else if token := res.Header.Get(NextPageTokenHeader); token != "" {
+		p.query.Set("page_token", token)
+		p.done = false
+	}
+
+	if !p.done && slice.Elem().Len() == 0 {
+		p.err = errEmptyPage
+		return false
+	}
+
+	return true
+}
+
+// Stop ends the iteration early, NextPage will return false without making
+// any further requests.
+func (p *Pager) Stop() {
+	p.done = true
+}
+
+// Response returns the HTTP response for the most recent page.
+func (p *Pager) Response() *http.Response {
+	return p.res
+}
+
+// Err returns the first error that occurred while fetching pages.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// token returns the page token that will be used for the next page.
+func (p *Pager) token() string {
+	if p.done {
+		return ""
+	}
+	return p.query.Get("page_token")
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]*)>\s*;[^,]*\brel="?next"?`)
+
+// linkNext returns the URL with rel="next" from an RFC 8288 Link header.
+func linkNext(header string) string {
+	match := linkNextPattern.FindStringSubmatch(header)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,137 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pagedServer serves pages of addresses keyed by page_token using the
+// X-Next-Page-Token header.
+func pagedServer(t *testing.T, pages map[string]string, next map[string]string,
+	requests *[]string) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("page_token")
+		*requests = append(*requests, r.URL.RawQuery)
+		if next[token] != "" {
+			w.Header().Set(NextPageTokenHeader, next[token])
+		}
+		w.Write([]byte(pages[token]))
+	}))
+}
+
+func TestPager(t *testing.T) {
+	pages := map[string]string{
+		"":   `[{"id":"1"},{"id":"2"}]`,
+		"p2": `[{"id":"3"}]`,
+		"p3": `[]`,
+	}
+	next := map[string]string{"": "p2"}
+
+	var requests []string
+	server := pagedServer(t, pages, next, &requests)
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+
+	t.Run("Next page tokens", func(t *testing.T) {
+		requests = nil
+		var ids []string
+		it := client.Addresses(context.Background(), ListOptions{PerPage: 2})
+		for it.Next() {
+			ids = append(ids, it.Address().ID)
+		}
+
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+
+		if !cmp.Equal([]string{"1", "2", "3"}, ids) {
+			t.Errorf("Unexpected addresses {%v}", ids)
+		}
+
+		expected := []string{"per_page=2", "page_token=p2&per_page=2"}
+		if !cmp.Equal(expected, requests) {
+			t.Errorf("Unexpected requests {%v}", cmp.Diff(expected, requests))
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		requests = nil
+		it := client.Addresses(context.Background(), ListOptions{})
+		it.Next()
+		it.Stop()
+
+		if it.Next() || len(requests) != 1 {
+			t.Errorf("Expected no further requests got {%v}", requests)
+		}
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := client.Addresses(ctx, ListOptions{})
+		it.Next()
+		it.Next()
+		cancel()
+
+		if it.Next() || !errors.Is(it.Err(), context.Canceled) {
+			t.Errorf("Expected context.Canceled got {%v}", it.Err())
+		}
+	})
+
+	t.Run("Empty page with a next page", func(t *testing.T) {
+		next["p2"] = "p3"
+		next["p3"] = "p4"
+		defer delete(next, "p2")
+		defer delete(next, "p3")
+
+		addresses, err := client.ListAddresses(context.Background())
+		if !errors.Is(err, errEmptyPage) || len(addresses) != 3 {
+			t.Errorf("Expected empty page error got {%v}, {%v}", err, addresses)
+		}
+	})
+
+	t.Run("Invalid out", func(t *testing.T) {
+		var out []Address
+		pager := client.NewPager(context.Background(), "/addresses", nil, RequestTypeAccount)
+		if pager.NextPage(out) || pager.Err() == nil {
+			t.Error("Expected error for non pointer out")
+		}
+	})
+}
+
+func TestPager_TruncatedPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"1"},{"id":`))
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, AccountID: "account", AccountToken: "token"}
+	_, err := client.ListDomains(context.Background())
+	if err == nil {
+		t.Error("Expected error for truncated page")
+	}
+}
+
+func TestLinkNext(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{`<https://example.com/a?page=2>; rel="next"`, "https://example.com/a?page=2"},
+		{`<https://example.com/1>; rel="prev", <https://example.com/3>; rel="next"`, "https://example.com/3"},
+		{`<https://example.com/1>; rel="prev"`, ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := linkNext(tt.header); got != tt.expected {
+			t.Errorf("Expected {%s} got {%s}", tt.expected, got)
+		}
+	}
+}
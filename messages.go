@@ -0,0 +1,179 @@
+package cloudmailin
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MessageStatus is the delivery state of a sent message.
+type MessageStatus string
+
+const (
+	MessageStatusQueued    MessageStatus = "queued"
+	MessageStatusDelivered MessageStatus = "delivered"
+	MessageStatusDeferred  MessageStatus = "deferred"
+	MessageStatusBounced   MessageStatus = "bounced"
+	MessageStatusFailed    MessageStatus = "failed"
+)
+
+// Message is the delivery record of a message sent with SendMail.
+type Message struct {
+	ID        string        `json:"id"`
+	From      string        `json:"from"`
+	To        []string      `json:"to"`
+	Subject   string        `json:"subject"`
+	Tags      []string      `json:"tags"`
+	Status    MessageStatus `json:"status"`
+	TestMode  bool          `json:"test_mode"`
+	CreatedAt time.Time     `json:"created_at"`
+
+	// DeliveredAt is zero until the message has been delivered.
+	DeliveredAt time.Time `json:"delivered_at"`
+
+	// Bounce is only present when the message bounced or failed.
+	Bounce *MessageBounce `json:"bounce,omitempty"`
+}
+
+// MessageBounce contains the details of a bounced message.
+type MessageBounce struct {
+	Type        string    `json:"type"`
+	Code        string    `json:"code"`
+	Recipient   string    `json:"recipient"`
+	Description string    `json:"description"`
+	BouncedAt   time.Time `json:"bounced_at"`
+}
+
+// ListMessagesOptions filters the messages returned by ListMessages. Empty
+// fields are ignored.
+type ListMessagesOptions struct {
+	Tag       string
+	Recipient string
+	Status    MessageStatus
+	After     time.Time
+	Before    time.Time
+
+	// PerPage is the number of messages requested in each page.
+	PerPage int
+
+	// PageToken requests a specific page, use MessagePage.NextPageToken.
+	PageToken string
+}
+
+// MessagePage is a single page of messages returned by ListMessages.
+type MessagePage struct {
+	Messages []Message
+
+	// NextPageToken is empty when this is the last page.
+	NextPageToken string
+}
+
+// query returns the URL query parameters for the options.
+func (opts ListMessagesOptions) query() url.Values {
+	query := url.Values{}
+	if opts.Tag != "" {
+		query.Set("tag", opts.Tag)
+	}
+	if opts.Recipient != "" {
+		query.Set("recipient", opts.Recipient)
+	}
+	if opts.Status != "" {
+		query.Set("status", string(opts.Status))
+	}
+	if !opts.After.IsZero() {
+		query.Set("after", opts.After.UTC().Format(time.RFC3339))
+	}
+	if !opts.Before.IsZero() {
+		query.Set("before", opts.Before.UTC().Format(time.RFC3339))
+	}
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	if opts.PageToken != "" {
+		query.Set("page_token", opts.PageToken)
+	}
+	return query
+}
+
+// GetMessage returns the delivery record for the message ID populated by
+// SendMail.
+func (client Client) GetMessage(ctx context.Context, id string) (message Message,
+	res *http.Response, err error) {
+
+	req, err := client.NewRequest(ctx, "GET", "/messages/"+url.PathEscape(id), nil, nil,
+		RequestTypeSMTP)
+	if err != nil {
+		return
+	}
+
+	res, err = client.DoRequest(req, &message)
+	return
+}
+
+// ListMessages returns a single page of sent messages matching opts. To walk
+// through every page use Messages.
+func (client Client) ListMessages(ctx context.Context, opts ListMessagesOptions) (
+	page MessagePage, res *http.Response, err error) {
+
+	pager := client.NewPager(ctx, "/messages", opts.query(), RequestTypeSMTP)
+	if !pager.NextPage(&page.Messages) {
+		return page, pager.Response(), pager.Err()
+	}
+
+	page.NextPageToken = pager.token()
+	return page, pager.Response(), nil
+}
+
+// Messages returns a MessageIterator that walks through every message
+// matching opts, fetching further pages as required.
+func (client Client) Messages(ctx context.Context, opts ListMessagesOptions) *MessageIterator {
+	return &MessageIterator{pager: client.NewPager(ctx, "/messages", opts.query(),
+		RequestTypeSMTP)}
+}
+
+// MessageIterator walks through the pages returned by ListMessages. Call
+// Next until it returns false then check Err:
+//
+//	it := client.Messages(ctx, opts)
+//	for it.Next() {
+//		fmt.Println(it.Message().ID)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type MessageIterator struct {
+	pager   *Pager
+	page    []Message
+	current Message
+}
+
+// Next advances to the next message, returning false at the end of the
+// messages or if an error occurred.
+func (it *MessageIterator) Next() bool {
+	for len(it.page) == 0 {
+		if !it.pager.NextPage(&it.page) {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Message returns the current message.
+func (it *MessageIterator) Message() Message {
+	return it.current
+}
+
+// Stop ends the iteration early without fetching any further pages.
+func (it *MessageIterator) Stop() {
+	it.pager.Stop()
+	it.page = nil
+}
+
+// Err returns the first error that occurred while fetching pages.
+func (it *MessageIterator) Err() error {
+	return it.pager.Err()
+}
@@ -0,0 +1,111 @@
+package cloudmailin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_GetMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/messages/abc123" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id":"abc123","status":"bounced","to":["debug@example.net"],` +
+			`"created_at":"2020-07-08T10:44:51Z","delivered_at":null,` +
+			`"bounce":{"type":"hard","code":"5.1.1","description":"No such user"}}`))
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+	message, _, err := client.GetMessage(context.Background(), "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Message{
+		ID:        "abc123",
+		To:        []string{"debug@example.net"},
+		Status:    MessageStatusBounced,
+		CreatedAt: time.Date(2020, 7, 8, 10, 44, 51, 0, time.UTC),
+		Bounce:    &MessageBounce{Type: "hard", Code: "5.1.1", Description: "No such user"},
+	}
+	if !cmp.Equal(expected, message) {
+		t.Errorf("Unexpected message {%v}", cmp.Diff(expected, message))
+	}
+}
+
+func TestClient_ListMessages(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("page_token") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/user/messages?page_token=p2>; rel="next"`,
+				"http://"+r.Host))
+			w.Write([]byte(`[{"id":"1"},{"id":"2"}]`))
+		case "p2":
+			w.Write([]byte(`[{"id":"3"}]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+	opts := ListMessagesOptions{
+		Tag:     "go",
+		Status:  MessageStatusDelivered,
+		After:   time.Date(2020, 7, 8, 0, 0, 0, 0, time.UTC),
+		PerPage: 2,
+	}
+
+	t.Run("Page", func(t *testing.T) {
+		queries = nil
+		page, _, err := client.ListMessages(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page.Messages) != 2 || page.NextPageToken != "p2" {
+			t.Errorf("Unexpected page {%v}", page)
+		}
+
+		expected := "after=2020-07-08T00%3A00%3A00Z&per_page=2&status=delivered&tag=go"
+		if queries[0] != expected {
+			t.Errorf("Unexpected query {%s}", cmp.Diff(expected, queries[0]))
+		}
+	})
+
+	t.Run("Iterator", func(t *testing.T) {
+		var ids []string
+		it := client.Messages(context.Background(), opts)
+		for it.Next() {
+			ids = append(ids, it.Message().ID)
+		}
+
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+
+		if !cmp.Equal([]string{"1", "2", "3"}, ids) {
+			t.Errorf("Unexpected messages {%v}", ids)
+		}
+	})
+
+	t.Run("Iterator error", func(t *testing.T) {
+		opts := opts
+		opts.PageToken = "invalid"
+		it := client.Messages(context.Background(), opts)
+		if it.Next() || !errors.Is(it.Err(), ErrValidation) {
+			t.Errorf("Expected ErrValidation got {%v}", it.Err())
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package cloudmailin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// maxMIMEDepth limits how deeply multipart messages may be nested.
+const maxMIMEDepth = 32
+
+// MIMEPart is a node in the MIME tree of an email message. Multipart
+// containers have Parts and no Body, every other part has its Body decoded
+// from the Content-Transfer-Encoding.
+type MIMEPart struct {
+	Header textproto.MIMEHeader
+
+	// MediaType is the lower case content type such as text/plain, it
+	// defaults to text/plain when missing.
+	MediaType string
+
+	// Params contains the Content-Type parameters such as charset.
+	Params map[string]string
+
+	Body  []byte
+	Parts []*MIMEPart
+}
+
+// ParseMIME parses a complete RFC 822 email message into a tree of
+// MIMEParts.
+func ParseMIME(r io.Reader) (*MIMEPart, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMIMEPart(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+}
+
+// parseMIMEPart parses a single part (and any children) from its header
+// and body.
+func parseMIMEPart(header textproto.MIMEHeader, body io.Reader, depth int) (*MIMEPart, error) {
+	if depth > maxMIMEDepth {
+		return nil, errors.New("mime: message is nested too deeply")
+	}
+
+	part := &MIMEPart{Header: header, MediaType: "text/plain", Params: map[string]string{}}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err == nil {
+			part.MediaType, part.Params = mediaType, params
+		}
+	}
+
+	if strings.HasPrefix(part.MediaType, "multipart/") && part.Params["boundary"] != "" {
+		reader := multipart.NewReader(body, part.Params["boundary"])
+		for {
+			p, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("mime: reading %s: %w", part.MediaType, err)
+			}
+
+			child, err := parseMIMEPart(p.Header, p, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			part.Parts = append(part.Parts, child)
+		}
+		return part, nil
+	}
+
+	var err error
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		part.Body, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding,
+			&base64Cleaner{r: body}))
+	case "quoted-printable":
+		part.Body, err = ioutil.ReadAll(quotedprintable.NewReader(body))
+	default:
+		part.Body, err = ioutil.ReadAll(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mime: decoding %s: %w", part.MediaType, err)
+	}
+
+	return part, nil
+}
+
+// FileName returns the decoded file name of the part from the
+// Content-Disposition or the Content-Type name parameter.
+func (p *MIMEPart) FileName() string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = p.Params["name"]
+	}
+	return decodeHeader(name)
+}
+
+// Disposition returns the lower case Content-Disposition such as attachment
+// or inline, or an empty string if it isn't set.
+func (p *MIMEPart) Disposition() string {
+	disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	return disposition
+}
+
+// Text returns the Body of a text part converted to UTF-8. Only UTF-8,
+// US-ASCII and ISO-8859-1 are converted, other character sets are
+// returned unchanged.
+func (p *MIMEPart) Text() string {
+	switch strings.ToLower(p.Params["charset"]) {
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(p.Body))
+		for i, b := range p.Body {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(p.Body)
+}
+
+// Walk calls fn for the part and each descendant in depth first order.
+func (p *MIMEPart) Walk(fn func(part *MIMEPart)) {
+	fn(p)
+	for _, child := range p.Parts {
+		child.Walk(fn)
+	}
+}
+
+var headerDecoder = &mime.WordDecoder{}
+
+// decodeHeader decodes any RFC 2047 encoded-words in value, returning the
+// original value if it cannot be decoded.
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// base64Cleaner strips whitespace that some mail clients leave in Base64
+// encoded bodies.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	clean := bytes.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, b[:n])
+	return copy(b, clean), err
+}
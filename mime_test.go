@@ -0,0 +1,76 @@
+package cloudmailin
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMIME(t *testing.T) {
+	data, _ := os.Open("test/fixtures/message.eml")
+	defer data.Close()
+
+	root, err := ParseMIME(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	root.Walk(func(part *MIMEPart) {
+		types = append(types, part.MediaType)
+	})
+
+	expected := []string{"multipart/mixed", "multipart/alternative", "text/plain",
+		"text/html", "image/png"}
+	if !cmp.Equal(expected, types) {
+		t.Errorf("Unexpected tree {%v}", cmp.Diff(expected, types))
+	}
+
+	attachment := root.Parts[1]
+	if attachment.FileName() != "pixel.png" || attachment.Disposition() != "attachment" ||
+		len(attachment.Body) != 70 {
+		t.Errorf("Unexpected attachment {%v}", attachment.Header)
+	}
+}
+
+func TestMIMEPart_Text(t *testing.T) {
+	message := "Content-Type: text/plain; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\nCaf=E9"
+
+	part, err := ParseMIME(strings.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if part.Text() != "Café" {
+		t.Errorf("Expected Latin-1 to be converted got {%s}", part.Text())
+	}
+}
+
+func TestMIMEPart_FileName(t *testing.T) {
+	message := "Content-Type: application/pdf; name=\"=?UTF-8?B?UmVwb3J0IOKckw==?=.pdf\"\r\n\r\n"
+
+	part, err := ParseMIME(strings.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if part.FileName() != "Report ✓.pdf" {
+		t.Errorf("Expected encoded name to be decoded got {%s}", part.FileName())
+	}
+}
+
+func TestParseMIME_Depth(t *testing.T) {
+	var message strings.Builder
+	for i := 0; i <= maxMIMEDepth+1; i++ {
+		fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=b%d\r\n\r\n--b%d\r\n", i, i)
+	}
+
+	_, err := ParseMIME(strings.NewReader(message.String()))
+	if err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("Expected error for deeply nested message got {%v}", err)
+	}
+}
@@ -1,9 +1,10 @@
 package cloudmailin
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path"
@@ -11,6 +12,10 @@ import (
 
 // OutboundMail represents an email message ready to be sent.
 // The ID will be populated by the API call once the message has been sent.
+//
+// The IdempotencyKey is sent as a header rather than in the message body. If
+// it is empty a new key is generated each time the message is sent, which is
+// only reused when the request is retried.
 type OutboundMail struct {
 	From        string                   `json:"from"`
 	To          []string                 `json:"to,omitempty"`
@@ -26,6 +31,8 @@ type OutboundMail struct {
 	TestMode    bool                     `json:"test_mode,omitempty"`
 
 	ID string `json:"id,omitempty"`
+
+	IdempotencyKey string `json:"-"`
 }
 
 // OutboundMailAttachment represents the format of attachments to be sent
@@ -45,23 +52,95 @@ type OutboundMailAttachment struct {
 }
 
 // SendMail will make a POST to send the OutboundMail email via the HTTP API.
+// Any response other than 202 Accepted is returned as an *APIError.
 func (client Client) SendMail(message *OutboundMail) (res *http.Response, err error) {
-	res, err = client.Do("POST", "/messages", message, RequestTypeSMTP)
+	return client.SendMailContext(context.Background(), message)
+}
+
+// SendMailContext sends the OutboundMail in the same way as SendMail but
+// uses ctx for the request, allowing the send to be cancelled or bounded by
+// a deadline.
+//
+// If the Client has an IdempotencyStore and the IdempotencyKey set on the
+// message has already been acknowledged, the recorded response is returned
+// without sending the message again.
+//
+// If the Client has PlainFromHTML set and the message has an HTML body but
+// no Plain body, Plain is set from the HTML before the message is sent.
+func (client Client) SendMailContext(ctx context.Context, message *OutboundMail) (
+	res *http.Response, err error) {
+
+	if client.PlainFromHTML && message.Plain == "" && message.HTML != "" {
+		message.Plain = HTMLToText(message.HTML)
+	}
+
+	// A generated key only protects the retries of this call so it isn't
+	// stored on the message or recorded in the IdempotencyStore.
+	key, store := message.IdempotencyKey, client.IdempotencyStore
+	if key == "" {
+		if key, err = NewIdempotencyKey(); err != nil {
+			return
+		}
+		store = nil
+	}
+
+	if store != nil {
+		if body, ok := store.Load(key); ok {
+			res = replayedResponse(body)
+			err = json.Unmarshal(body, message)
+			return
+		}
+	}
+
+	req, err := client.NewRequest(ctx, "POST", "/messages", nil, message, RequestTypeSMTP)
+	if err != nil {
+		return
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+
+	res, err = client.do(req)
 	if err != nil {
 		return
 	}
 
 	if res.StatusCode != 202 {
-		body, _ := ioutil.ReadAll(res.Body)
-		err = fmt.Errorf("could not send message (%d): %s", res.StatusCode, body)
-	} else {
-		defer res.Body.Close()
-		err = json.NewDecoder(res.Body).Decode(message)
+		err = newAPIError(res)
+		return
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(body, message); err != nil {
+		return
+	}
+
+	if store != nil && message.ID != "" {
+		store.Store(key, body)
 	}
 
 	return
 }
 
+// replayedResponse builds the response returned when a send is answered
+// from the IdempotencyStore.
+func replayedResponse(body []byte) *http.Response {
+	return &http.Response{
+		Status:        "202 Accepted",
+		StatusCode:    202,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}, "Idempotent-Replayed": {"true"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
 // AttachmentFromFile is a convenience function to prepare an OutboundMailAttachment
 // from a local file (given as the filepath argument).
 // The content will be Base64 encoded automatically and the filename included.
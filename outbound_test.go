@@ -1,7 +1,12 @@
 package cloudmailin
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -90,6 +95,45 @@ func TestClient_SendMessage_404(t *testing.T) {
 	}
 }
 
+func TestClient_SendMailContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message OutboundMail
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		message.ID = "abc123"
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(message)
+	}))
+	defer server.Close()
+
+	client := Client{BaseURL: server.URL, SMTPAccountID: "user", SMTPToken: "pass"}
+
+	t.Run("Send", func(t *testing.T) {
+		message := buildMessage()
+		res, err := client.SendMailContext(context.Background(), &message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != 202 || message.ID != "abc123" {
+			t.Errorf("Expected 202 and ID got {%v}, {%v}", res.StatusCode, message.ID)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		message := buildMessage()
+		_, err := client.SendMailContext(ctx, &message)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled got {%v}", err)
+		}
+	})
+}
+
 func TestOutboundMailAttachment_AttachmentFromFile(t *testing.T) {
 	t.Run("Invalid file", func(t *testing.T) {
 		path := "test/fixtures/missing.png"
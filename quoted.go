@@ -0,0 +1,264 @@
+package cloudmailin
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Reply is an email split into the new text written by the sender and the
+// history they were replying to.
+type Reply struct {
+	// Text is the new content of the reply without the signature.
+	Text string
+
+	// Signature is the sender's signature or mobile footer, if found.
+	Signature string
+
+	// Quoted is the quoted history including the attribution line such as
+	// "On ... wrote:".
+	Quoted string
+}
+
+var (
+	// attributionPattern matches the line introducing a quoted message such
+	// as "On Wed, 8 Jul 2020, Steve <steve@example.com> wrote:". German and
+	// Dutch clients put the name after the verb.
+	attributionPattern = regexp.MustCompile(`(?i)(\bwrote|\ba écrit|\bschrieb|\bescribió|` +
+		`\bha scritto|\bschreef|\bescreveu|\bskrev|\bnapisał\(a\)|\bkirjoitti|` +
+		`\bнаписал\(а\)|写道)\s*:\s*$|^(Am|Op)\s.*\b(schrieb|schreef)\s.*:\s*$`)
+
+	// attributionStartPattern matches the start of an attribution, an
+	// attribution that doesn't start this way must include a date, time or
+	// address and come before the quoted lines.
+	attributionStartPattern  = regexp.MustCompile(`(?i)^(On|Le|Am|El|Il|Op|Em|Den|På|W dniu)\s`)
+	attributionDetailPattern = regexp.MustCompile(`\S@\S|\d:\d\d|\b(19|20)\d\d\b`)
+
+	// separatorPattern matches the separators added by Outlook and other
+	// clients above a forwarded or quoted message.
+	separatorPattern = regexp.MustCompile(`(?i)^\s*(-{2,}\s*(Original Message|Ursprüngliche Nachricht|` +
+		`Message d'origine|Mensaje original|Messaggio originale|Oorspronkelijk bericht|` +
+		`Mensagem original|Ursprungligt meddelande|Forwarded message)\s*-{2,}|_{20,})\s*$`)
+
+	// headerFromPattern and headerNextPattern match the block of headers
+	// Outlook adds above a quoted message, such as From: followed by Sent:.
+	// The block must include a subject matched by headerSubjectPattern or be
+	// followed by quoted lines.
+	headerFromPattern = regexp.MustCompile(`(?i)^\s*\*?(From|De|Von|Van|Da|Från|Fra|Od)\s?:`)
+	headerNextPattern = regexp.MustCompile(`(?i)^\s*\*?(Sent|Date|Envoyé|Gesendet|Enviado|` +
+		`Enviada|Verzonden|Inviato|Skickat|Sendt|Datum|Data|Fecha|Wysłano|To|À|An|Para|Aan|A|Till|` +
+		`Til|Do|Cc|Subject|Objet|Betreff|Asunto|Oggetto|Onderwerp|Assunto|Ämne|Emne|Temat)\s?:`)
+	headerSubjectPattern = regexp.MustCompile(`(?i)^\s*\*?(Subject|Objet|Betreff|Asunto|Oggetto|` +
+		`Onderwerp|Assunto|Ämne|Emne|Temat)\s?:`)
+
+	// footerPattern matches the footers added by mobile mail clients.
+	footerPattern = regexp.MustCompile(`(?i)^\s*(Sent from my |Sent from |Sent via |Get Outlook for |` +
+		`Envoyé de mon |Envoyé depuis |Von meinem .* gesendet|Gesendet von |Enviado desde |` +
+		`Enviado do meu |Enviado de mi |Inviato da |Verzonden (vanaf|met) |Skickat från |Sendt fra |` +
+		`Wysłane z )`)
+)
+
+// SplitReply splits a plain text email into the reply, signature and quoted
+// history.
+//
+// The quoted history starts at the first attribution line ("On ... wrote:"
+// and its equivalents in other languages), Outlook separator or header block,
+// or at the trailing block of lines quoted with ">". Replies written
+// between quoted lines are kept as part of the Text. The signature starts at
+// a "-- " line or a mobile footer such as "Sent from my iPhone".
+func SplitReply(plain string) Reply {
+	lines := strings.Split(strings.Replace(plain, "\r\n", "\n", -1), "\n")
+
+	quote := quoteStart(lines)
+	reply := Reply{Quoted: strings.TrimSpace(strings.Join(lines[quote:], "\n"))}
+
+	lines = lines[:quote]
+	signature := signatureStart(lines)
+	reply.Signature = strings.TrimSpace(strings.Join(lines[signature:], "\n"))
+	reply.Text = strings.TrimSpace(strings.Join(lines[:signature], "\n"))
+
+	return reply
+}
+
+// quoteStart returns the index of the first line of the quoted history, or
+// len(lines) if there is none.
+func quoteStart(lines []string) int {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+
+		if separatorPattern.MatchString(trimmed) {
+			return i
+		}
+
+		next := nextLine(lines, i)
+		if isAttribution(trimmed) && (attributionStartPattern.MatchString(trimmed) ||
+			next < 0 || strings.HasPrefix(strings.TrimSpace(lines[next]), ">")) {
+			return i
+		}
+
+		if next >= 0 && attributionStartPattern.MatchString(trimmed) &&
+			attributionPattern.MatchString(strings.TrimSpace(lines[next])) {
+			return i
+		}
+
+		if headerFromPattern.MatchString(trimmed) && isHeaderBlock(lines, i) {
+			return i
+		}
+	}
+
+	// Otherwise the trailing block of quoted and blank lines.
+	start := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed != "" && !strings.HasPrefix(trimmed, ">") {
+			break
+		}
+		if trimmed != "" {
+			start = i
+		}
+	}
+	return start
+}
+
+// signatureStart returns the index of the first line of the signature, or
+// len(lines) if there is none.
+func signatureStart(lines []string) int {
+	for i, line := range lines {
+		if trimmed := strings.TrimRight(line, " \t"); trimmed == "--" {
+			return i
+		}
+	}
+
+	// Mobile footers are only recognised at the end of the reply.
+	for i := len(lines) - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if footerPattern.MatchString(trimmed) {
+			return i
+		}
+		break
+	}
+
+	return len(lines)
+}
+
+// isAttribution reports whether line could introduce a quoted message. In
+// plain text a line without an attribution prefix must also be followed by
+// quoted lines or the end of the message.
+func isAttribution(line string) bool {
+	return attributionPattern.MatchString(line) &&
+		(attributionStartPattern.MatchString(line) || attributionDetailPattern.MatchString(line))
+}
+
+// isHeaderBlock reports whether the From: line at i starts a block of headers
+// that includes a subject or is followed by quoted lines.
+func isHeaderBlock(lines []string, i int) bool {
+	headers := 0
+	for i = nextLine(lines, i); i >= 0; i = nextLine(lines, i) {
+		trimmed := strings.TrimSpace(lines[i])
+		if headerSubjectPattern.MatchString(trimmed) {
+			return true
+		}
+		if !headerNextPattern.MatchString(trimmed) {
+			return headers > 0 && strings.HasPrefix(trimmed, ">")
+		}
+		headers++
+	}
+	return false
+}
+
+// nextLine returns the index of the next non-blank line after i, or -1.
+func nextLine(lines []string, i int) int {
+	for i++; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	// htmlQuotePattern matches the elements that mail clients wrap around
+	// the quoted history of an HTML email.
+	htmlQuotePattern = regexp.MustCompile(`(?i)<[a-z]+[^>]*(class="[^"]*\b(gmail_quote|yahoo_quoted|` +
+		`moz-cite-prefix|protonmail_quote)\b|id="(appendonsend|divRplyFwdMsg|stopSpelling|` +
+		`mail-editor-reference-message-container)"|border-top:\s*solid\s+#(E1E1E1|B5C4DF))[^>]*>|` +
+		`<blockquote\b[^>]*>`)
+
+	// htmlSignaturePattern matches the elements that mail clients wrap
+	// around the signature.
+	htmlSignaturePattern = regexp.MustCompile(`(?i)<[a-z]+[^>]*(class="[^"]*\b(gmail_signature|` +
+		`moz-signature)\b|id="(Signature|ms-outlook-mobile-signature)")[^>]*>`)
+
+	htmlBlockPattern = regexp.MustCompile(`(?i)<(div|p|br)\b`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// SplitReplyHTML splits an HTML email into the reply, signature and quoted
+// history, each as a fragment of the original HTML.
+//
+// The quoted history starts at the elements mail clients use to wrap it, such
+// as Gmail's gmail_quote, a blockquote or Outlook's separator, including any
+// attribution ("On ... wrote:") immediately before it. The signature is found
+// using the classes and IDs added by Gmail, Thunderbird and Outlook.
+func SplitReplyHTML(body string) Reply {
+	quote := len(body)
+	if loc := htmlQuotePattern.FindStringIndex(body); loc != nil {
+		quote = attributionStartHTML(body[:loc[0]])
+	}
+
+	reply := Reply{Quoted: strings.TrimSpace(body[quote:])}
+	body = body[:quote]
+
+	if loc := htmlSignaturePattern.FindStringIndex(body); loc != nil {
+		reply.Signature = strings.TrimSpace(body[loc[0]:])
+		body = body[:loc[0]]
+	}
+	reply.Text = strings.TrimSpace(body)
+
+	return reply
+}
+
+// attributionStartHTML returns the start of an attribution at the end of
+// body, or len(body) if there isn't one.
+func attributionStartHTML(body string) int {
+	blocks := htmlBlockPattern.FindAllStringIndex(body, -1)
+	for i := len(blocks) - 1; i >= 0 && i >= len(blocks)-3; i-- {
+		text := html.UnescapeString(htmlTagPattern.ReplaceAllString(body[blocks[i][0]:], " "))
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		if isAttribution(text) {
+			return blocks[i][0]
+		}
+		break
+	}
+	return len(body)
+}
+
+// Reply splits the Plain body of the email into the reply and quoted
+// history, see SplitReply. If the email only has an HTML body it is split
+// with SplitReplyHTML and each part converted to text.
+func (m IncomingMail) Reply() Reply {
+	if strings.TrimSpace(m.Plain) == "" && m.HTML != "" {
+		reply := SplitReplyHTML(m.HTML)
+		return Reply{
+			Text:      HTMLToText(reply.Text),
+			Signature: HTMLToText(reply.Signature),
+			Quoted:    HTMLToText(reply.Quoted),
+		}
+	}
+	return SplitReply(m.Plain)
+}
+
+// ReplyHTML splits the HTML body of the email into the reply and quoted
+// history, see SplitReplyHTML.
+func (m IncomingMail) ReplyHTML() Reply {
+	return SplitReplyHTML(m.HTML)
+}
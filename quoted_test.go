@@ -0,0 +1,218 @@
+package cloudmailin
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		plain    string
+		expected Reply
+	}{
+		{
+			"Gmail",
+			"Thanks, that works.\r\n\r\nOn Wed, 8 Jul 2020 at 10:00, Steve <steve@example.com> wrote:\r\n" +
+				"> Does this work?\r\n",
+			Reply{
+				Text:   "Thanks, that works.",
+				Quoted: "On Wed, 8 Jul 2020 at 10:00, Steve <steve@example.com> wrote:\n> Does this work?",
+			},
+		},
+		{
+			"Wrapped Attribution",
+			"Yes\n\nOn Wed, 8 Jul 2020 at 10:00, Steve Smith <\nsteve@example.com> wrote:\n\n> Question\n",
+			Reply{
+				Text:   "Yes",
+				Quoted: "On Wed, 8 Jul 2020 at 10:00, Steve Smith <\nsteve@example.com> wrote:\n\n> Question",
+			},
+		},
+		{
+			"French",
+			"Merci\n\nLe mer. 8 juil. 2020 à 10:00, Steve <steve@example.com> a écrit :\n> Bonjour\n",
+			Reply{Text: "Merci", Quoted: "Le mer. 8 juil. 2020 à 10:00, Steve <steve@example.com> a écrit :\n> Bonjour"},
+		},
+		{
+			"German",
+			"Danke\n\nAm 08.07.2020 um 10:00 schrieb Steve <steve@example.com>:\n> Hallo\n",
+			Reply{Text: "Danke", Quoted: "Am 08.07.2020 um 10:00 schrieb Steve <steve@example.com>:\n> Hallo"},
+		},
+		{
+			"Outlook Separator",
+			"See below\n\n-----Original Message-----\nFrom: Steve\nSubject: Hi\n\nHello\n",
+			Reply{Text: "See below", Quoted: "-----Original Message-----\nFrom: Steve\nSubject: Hi\n\nHello"},
+		},
+		{
+			"Outlook Underscores",
+			"See below\n\n________________________________\nFrom: Steve\nHello\n",
+			Reply{Text: "See below", Quoted: "________________________________\nFrom: Steve\nHello"},
+		},
+		{
+			"Outlook Headers",
+			"Sounds good\n\nFrom: Steve Smith <steve@example.com>\nSent: Wednesday, July 8, 2020 10:00 AM\n" +
+				"To: Support\nSubject: Hi\n\nHello\n",
+			Reply{
+				Text: "Sounds good",
+				Quoted: "From: Steve Smith <steve@example.com>\nSent: Wednesday, July 8, 2020 10:00 AM\n" +
+					"To: Support\nSubject: Hi\n\nHello",
+			},
+		},
+		{
+			"Spanish Outlook Headers",
+			"Vale\n\nDe: Steve\nEnviado: miércoles\nPara: Soporte\nAsunto: Hola\n\nHola\n",
+			Reply{Text: "Vale", Quoted: "De: Steve\nEnviado: miércoles\nPara: Soporte\nAsunto: Hola\n\nHola"},
+		},
+		{
+			"Headers Before Quote",
+			"Ok\n\nFrom: Steve\nTo: Support\n\n> Hello\n",
+			Reply{Text: "Ok", Quoted: "From: Steve\nTo: Support\n\n> Hello"},
+		},
+		{
+			"Headers In Text",
+			"Please address it as:\nFrom: Accounts\nTo: Billing\n\nThanks\n",
+			Reply{Text: "Please address it as:\nFrom: Accounts\nTo: Billing\n\nThanks"},
+		},
+		{
+			"Address Attribution",
+			"Ok\n\nSteve Smith <steve@example.com> wrote:\n> Hi\n",
+			Reply{Text: "Ok", Quoted: "Steve Smith <steve@example.com> wrote:\n> Hi"},
+		},
+		{
+			"Wrote With Time In Text",
+			"Hi,\nThe meeting is at 10:00 and John wrote:\nsomething important.\nCheers",
+			Reply{Text: "Hi,\nThe meeting is at 10:00 and John wrote:\nsomething important.\nCheers"},
+		},
+		{
+			"Wrote In Text",
+			"Thanks, here is the summary of what the customer wrote:\n\n- item one\n- item two\n",
+			Reply{Text: "Thanks, here is the summary of what the customer wrote:\n\n- item one\n- item two"},
+		},
+		{
+			"Trailing Quote",
+			"Test Content\n\n> Example message\n> Option: 2\n> \n\n",
+			Reply{Text: "Test Content", Quoted: "> Example message\n> Option: 2\n>"},
+		},
+		{
+			"Interleaved",
+			"> Question 1\nAnswer 1\n\n> Question 2\nAnswer 2\n",
+			Reply{Text: "> Question 1\nAnswer 1\n\n> Question 2\nAnswer 2"},
+		},
+		{
+			"Signature",
+			"Thanks\n\n-- \nSteve Smith\nCloudMailin\n\nOn Wed, 8 Jul 2020, Support wrote:\n> Hi\n",
+			Reply{
+				Text:      "Thanks",
+				Signature: "-- \nSteve Smith\nCloudMailin",
+				Quoted:    "On Wed, 8 Jul 2020, Support wrote:\n> Hi",
+			},
+		},
+		{
+			"iPhone",
+			"Ok\n\nSent from my iPhone\n\n> On 8 Jul 2020, at 10:00, Support wrote:\n> Hi\n",
+			Reply{
+				Text:      "Ok",
+				Signature: "Sent from my iPhone",
+				Quoted:    "> On 8 Jul 2020, at 10:00, Support wrote:\n> Hi",
+			},
+		},
+		{"German iPhone", "Ok\n\nVon meinem iPhone gesendet", Reply{Text: "Ok", Signature: "Von meinem iPhone gesendet"}},
+		{"Outlook Mobile", "Ok\n\nGet Outlook for Android\n", Reply{Text: "Ok", Signature: "Get Outlook for Android"}},
+		{"Footer Mid Text", "Sent from my iPhone is a footer\nreally", Reply{
+			Text: "Sent from my iPhone is a footer\nreally",
+		}},
+		{"No Quote", "Just a message\n", Reply{Text: "Just a message"}},
+		{"Empty", "", Reply{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := SplitReply(tt.plain)
+			if !cmp.Equal(tt.expected, reply) {
+				t.Errorf("Expected {%v} got {%v}\n%v", tt.expected, reply, cmp.Diff(tt.expected, reply))
+			}
+		})
+	}
+}
+
+func TestSplitReplyHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected Reply
+	}{
+		{
+			"Gmail",
+			`<div dir="ltr">Thanks<div><br></div><div class="gmail_signature">Steve</div></div><br>` +
+				`<div class="gmail_quote"><div dir="ltr" class="gmail_attr">On Wed, Steve wrote:<br></div>` +
+				`<blockquote class="gmail_quote">Hi</blockquote></div>`,
+			Reply{
+				Text:      `<div dir="ltr">Thanks<div><br></div>`,
+				Signature: `<div class="gmail_signature">Steve</div></div><br>`,
+				Quoted: `<div class="gmail_quote"><div dir="ltr" class="gmail_attr">On Wed, Steve wrote:<br></div>` +
+					`<blockquote class="gmail_quote">Hi</blockquote></div>`,
+			},
+		},
+		{
+			"Apple Mail",
+			`<div>Thanks</div><div><br><div>On 8 Jul 2020, at 10:00, Steve &lt;steve@example.com&gt; wrote:</div>` +
+				`<br><blockquote type="cite"><div>Hi</div></blockquote></div>`,
+			Reply{
+				Text: `<div>Thanks</div><div><br>`,
+				Quoted: `<div>On 8 Jul 2020, at 10:00, Steve &lt;steve@example.com&gt; wrote:</div>` +
+					`<br><blockquote type="cite"><div>Hi</div></blockquote></div>`,
+			},
+		},
+		{
+			"Outlook",
+			`<p>See below</p><div id="appendonsend"></div><hr><div id="divRplyFwdMsg"><b>From:</b> Steve</div>`,
+			Reply{
+				Text:   `<p>See below</p>`,
+				Quoted: `<div id="appendonsend"></div><hr><div id="divRplyFwdMsg"><b>From:</b> Steve</div>`,
+			},
+		},
+		{
+			"Outlook Desktop",
+			`<p>Ok</p><div style="border:none;border-top:solid #E1E1E1 1.0pt;padding:3.0pt 0cm 0cm 0cm">` +
+				`<p><b>From:</b> Steve</p></div>`,
+			Reply{
+				Text: `<p>Ok</p>`,
+				Quoted: `<div style="border:none;border-top:solid #E1E1E1 1.0pt;padding:3.0pt 0cm 0cm 0cm">` +
+					`<p><b>From:</b> Steve</p></div>`,
+			},
+		},
+		{
+			"Wrote In Text",
+			`<p>The customer wrote:</p><blockquote>Hi</blockquote>`,
+			Reply{Text: `<p>The customer wrote:</p>`, Quoted: `<blockquote>Hi</blockquote>`},
+		},
+		{"No Quote", `<p>Hello</p>`, Reply{Text: `<p>Hello</p>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := SplitReplyHTML(tt.html)
+			if !cmp.Equal(tt.expected, reply) {
+				t.Errorf("Expected {%v} got {%v}\n%v", tt.expected, reply, cmp.Diff(tt.expected, reply))
+			}
+		})
+	}
+}
+
+func TestIncomingMail_Reply(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	if reply := message.Reply(); reply.Text != strings.TrimSpace(message.ReplyPlain) {
+		t.Errorf("Expected {%v} got {%v}", message.ReplyPlain, reply.Text)
+	}
+
+	expected := `<div dir="ltr">Test Content<div><br></div></div>`
+	if reply := message.ReplyHTML(); reply.Text != expected || reply.Quoted != "" {
+		t.Errorf("Expected {%v} got {%v}", expected, reply)
+	}
+}
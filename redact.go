@@ -0,0 +1,34 @@
+package cloudmailin
+
+import (
+	"fmt"
+)
+
+// redacted replaces secret values in String and GoString output.
+const redacted = "[REDACTED]"
+
+// redact masks a secret, an empty value is left empty to show that it is
+// missing.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// String implements fmt.Stringer showing the Client settings with the
+// tokens masked. This is also used for the %v and %+v verbs.
+func (client Client) String() string {
+	return fmt.Sprintf("{BaseURL:%s SMTPAccountID:%s SMTPToken:%s AccountID:%s AccountToken:%s}",
+		client.BaseURL, client.SMTPAccountID, redact(client.SMTPToken),
+		client.AccountID, redact(client.AccountToken))
+}
+
+// GoString implements fmt.GoStringer for the %#v verb with the tokens
+// masked.
+func (client Client) GoString() string {
+	return fmt.Sprintf("cloudmailin.Client{BaseURL:%q, SMTPAccountID:%q, SMTPToken:%q, "+
+		"AccountID:%q, AccountToken:%q}",
+		client.BaseURL, client.SMTPAccountID, redact(client.SMTPToken),
+		client.AccountID, redact(client.AccountToken))
+}
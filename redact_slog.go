@@ -0,0 +1,20 @@
+//go:build go1.21
+// +build go1.21
+
+package cloudmailin
+
+import (
+	"log/slog"
+)
+
+// LogValue implements slog.LogValuer so that a Client can be logged with
+// the tokens masked.
+func (client Client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("base_url", client.BaseURL),
+		slog.String("smtp_account_id", client.SMTPAccountID),
+		slog.String("smtp_token", redact(client.SMTPToken)),
+		slog.String("account_id", client.AccountID),
+		slog.String("account_token", redact(client.AccountToken)),
+	)
+}
@@ -0,0 +1,25 @@
+//go:build go1.21
+// +build go1.21
+
+package cloudmailin
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestClient_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	client := Client{SMTPAccountID: "user", SMTPToken: leakSMTPToken, AccountToken: leakAccountToken}
+	logger.Info("client", "client", client)
+
+	assertNoLeak(t, "slog", buf.String())
+	if !strings.Contains(buf.String(), "client.smtp_account_id=user") ||
+		!strings.Contains(buf.String(), redacted) {
+		t.Errorf("Expected masked client got {%s}", buf.String())
+	}
+}
@@ -0,0 +1,174 @@
+package cloudmailin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoReplyToken is returned when an address doesn't contain a reply
+	// token.
+	ErrNoReplyToken = errors.New("no reply token")
+
+	// ErrInvalidReplyToken is returned when a reply token is malformed or
+	// wasn't signed with the secret.
+	ErrInvalidReplyToken = errors.New("invalid reply token")
+
+	// ErrExpiredReplyToken is returned when a reply token was valid but has
+	// expired.
+	ErrExpiredReplyToken = errors.New("reply token has expired")
+)
+
+const (
+	replyMACSize       = 10
+	maxLocalPartLength = 64
+)
+
+// Tokens are lower case as some mail servers change the case of the local
+// part.
+var replyEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").
+	WithPadding(base32.NoPadding)
+
+// ReplySigner creates and verifies signed reply tokens. A token embeds an ID,
+// such as a ticket number, in a reply address like
+// reply+<token>@example.com so that replies to an email can be matched to
+// the record they relate to.
+//
+// Tokens are signed with an HMAC of the Secret so they can't be forged and
+// expire after TTL if it is set. The local part of an email address is
+// limited to 64 characters which limits IDs to around 20 bytes.
+type ReplySigner struct {
+	Secret []byte
+
+	// TTL is how long tokens are valid for, zero tokens don't expire.
+	TTL time.Duration
+
+	now func() time.Time
+}
+
+// NewReplySigner returns a ReplySigner using secret with tokens that don't
+// expire.
+func NewReplySigner(secret []byte) *ReplySigner {
+	return &ReplySigner{Secret: secret}
+}
+
+// Token returns a signed token for id.
+func (s *ReplySigner) Token(id string) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", errors.New("reply signer has no secret")
+	}
+	if id == "" {
+		return "", errors.New("reply token id is empty")
+	}
+
+	var expires uint64
+	if s.TTL > 0 {
+		expires = uint64(s.time().Add(s.TTL).Unix())
+	}
+
+	payload := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(id)+replyMACSize)
+	payload = append(payload[:binary.PutUvarint(payload, expires)], id...)
+	payload = append(payload, s.mac(payload)...)
+
+	return replyEncoding.EncodeToString(payload), nil
+}
+
+// Address returns base with a signed token for id added as the tag, for
+// example reply@example.com becomes reply+<token>@example.com. The address
+// can be used as the From or Reply-To of an OutboundMail.
+func (s *ReplySigner) Address(base string, id string) (string, error) {
+	address, err := ParseEnvelopeAddress(base)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.Token(id)
+	if err != nil {
+		return "", err
+	}
+
+	local := address.User + "+" + token
+	if len(local) > maxLocalPartLength {
+		return "", fmt.Errorf("reply address local part is longer than %d characters",
+			maxLocalPartLength)
+	}
+	return local + "@" + address.Domain, nil
+}
+
+// Verify checks token and returns the ID it contains.
+func (s *ReplySigner) Verify(token string) (id string, err error) {
+	if len(s.Secret) == 0 {
+		return "", errors.New("reply signer has no secret")
+	}
+
+	payload, err := replyEncoding.DecodeString(strings.ToLower(token))
+	if err != nil || len(payload) <= replyMACSize {
+		return "", ErrInvalidReplyToken
+	}
+
+	data, mac := payload[:len(payload)-replyMACSize], payload[len(payload)-replyMACSize:]
+	if !hmac.Equal(mac, s.mac(data)) {
+		return "", ErrInvalidReplyToken
+	}
+
+	expires, n := binary.Uvarint(data)
+	if n <= 0 || n == len(data) {
+		return "", ErrInvalidReplyToken
+	}
+	if expires > 0 && s.time().Unix() > int64(expires) {
+		return "", ErrExpiredReplyToken
+	}
+
+	return string(data[n:]), nil
+}
+
+// VerifyAddress verifies the token in the tag of address, such as
+// reply+<token>@example.com, and returns the ID it contains.
+func (s *ReplySigner) VerifyAddress(address string) (id string, err error) {
+	parsed, err := ParseEnvelopeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Tag == "" {
+		return "", ErrNoReplyToken
+	}
+	return s.Verify(parsed.Tag)
+}
+
+// VerifyEnvelope returns the ID from the first valid reply token in the
+// envelope To address or Recipients. If no address has a valid token the
+// error for the first token found is returned, or ErrNoReplyToken.
+func (s *ReplySigner) VerifyEnvelope(envelope IncomingMailEnvelope) (id string, err error) {
+	err = ErrNoReplyToken
+	for _, address := range append([]string{envelope.To}, envelope.Recipients...) {
+		found, verifyErr := s.VerifyAddress(address)
+		if verifyErr == nil {
+			return found, nil
+		}
+		if err == ErrNoReplyToken &&
+			(verifyErr == ErrInvalidReplyToken || verifyErr == ErrExpiredReplyToken) {
+			err = verifyErr
+		}
+	}
+	return "", err
+}
+
+func (s *ReplySigner) mac(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte("cloudmailin reply token\x00"))
+	mac.Write(data)
+	return mac.Sum(nil)[:replyMACSize]
+}
+
+func (s *ReplySigner) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,143 @@
+package cloudmailin
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplySigner(t *testing.T) {
+	signer := NewReplySigner([]byte("secret"))
+
+	address, err := signer.Address("Reply@Example.com", "ticket-1234")
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was {%v}", err)
+	}
+	if !strings.HasPrefix(address, "Reply+") || !strings.HasSuffix(address, "@example.com") {
+		t.Errorf("Expected {Reply+<token>@example.com} got {%v}", address)
+	}
+
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"Address", address},
+		{"Upper Case", strings.ToUpper(address)},
+		{"Angle Brackets", "<" + address + ">"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := signer.VerifyAddress(tt.address)
+			if err != nil || id != "ticket-1234" {
+				t.Errorf("Expected {ticket-1234} got {%v} (%v)", id, err)
+			}
+		})
+	}
+
+	token := strings.TrimPrefix(strings.Split(address, "@")[0], "Reply+")
+	forged := []byte(token)
+	forged[3] ^= 1
+
+	errorTests := []struct {
+		name     string
+		signer   *ReplySigner
+		address  string
+		expected error
+	}{
+		{"No Token", signer, "reply@example.com", ErrNoReplyToken},
+		{"Wrong Secret", NewReplySigner([]byte("other")), address, ErrInvalidReplyToken},
+		{"Forged", signer, "reply+" + string(forged) + "@example.com", ErrInvalidReplyToken},
+		{"Truncated", signer, "reply+" + token[:len(token)-2] + "@example.com", ErrInvalidReplyToken},
+		{"Not Base32", signer, "reply+1234@example.com", ErrInvalidReplyToken},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.signer.VerifyAddress(tt.address)
+			if err != tt.expected || id != "" {
+				t.Errorf("Expected {%v} got {%v} (%v)", tt.expected, err, id)
+			}
+		})
+	}
+}
+
+func TestReplySigner_Expiry(t *testing.T) {
+	now := time.Date(2020, 7, 8, 10, 0, 0, 0, time.UTC)
+	signer := &ReplySigner{Secret: []byte("secret"), TTL: time.Hour, now: func() time.Time { return now }}
+
+	token, err := signer.Token("1234")
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was {%v}", err)
+	}
+
+	if id, err := signer.Verify(token); err != nil || id != "1234" {
+		t.Errorf("Expected {1234} got {%v} (%v)", id, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := signer.Verify(token); err != ErrExpiredReplyToken {
+		t.Errorf("Expected {%v} got {%v}", ErrExpiredReplyToken, err)
+	}
+
+	// A token that doesn't expire is still accepted by a signer with a TTL.
+	signer.TTL = 0
+	token, _ = signer.Token("1234")
+	signer.TTL = time.Hour
+	now = now.Add(24 * time.Hour)
+	if id, err := signer.Verify(token); err != nil || id != "1234" {
+		t.Errorf("Expected {1234} got {%v} (%v)", id, err)
+	}
+}
+
+func TestReplySigner_VerifyEnvelope(t *testing.T) {
+	signer := NewReplySigner([]byte("secret"))
+	address, _ := signer.Address("reply@example.com", "42")
+	other, _ := NewReplySigner([]byte("other")).Address("reply@example.com", "43")
+
+	tests := []struct {
+		name     string
+		envelope IncomingMailEnvelope
+		id       string
+		err      error
+	}{
+		{"To", IncomingMailEnvelope{To: address}, "42", nil},
+		{"Recipients", IncomingMailEnvelope{
+			To:         "support@example.com",
+			Recipients: []string{"support@example.com", address},
+		}, "42", nil},
+		{"Valid After Invalid", IncomingMailEnvelope{
+			To:         other,
+			Recipients: []string{other, address},
+		}, "42", nil},
+		{"Forged", IncomingMailEnvelope{To: other, Recipients: []string{other}}, "", ErrInvalidReplyToken},
+		{"None", IncomingMailEnvelope{To: "support@example.com"}, "", ErrNoReplyToken},
+		{"Empty", IncomingMailEnvelope{}, "", ErrNoReplyToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := signer.VerifyEnvelope(tt.envelope)
+			if id != tt.id || err != tt.err {
+				t.Errorf("Expected {%v %v} got {%v %v}", tt.id, tt.err, id, err)
+			}
+		})
+	}
+}
+
+func TestReplySigner_Errors(t *testing.T) {
+	if _, err := (&ReplySigner{}).Token("1234"); err == nil {
+		t.Error("Expected error without a secret but was nil")
+	}
+
+	signer := NewReplySigner([]byte("secret"))
+	if _, err := signer.Token(""); err == nil {
+		t.Error("Expected error for an empty id but was nil")
+	}
+	if _, err := signer.Address("reply", "1234"); err == nil {
+		t.Error("Expected error for an invalid address but was nil")
+	}
+	if _, err := signer.Address("reply@example.com", strings.Repeat("x", 40)); err == nil {
+		t.Error("Expected error for a long id but was nil")
+	}
+}
@@ -0,0 +1,161 @@
+package cloudmailin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how the Client retries requests that fail with a
+// transient error. A transient error is a 429, 500, 502, 503 or 504 response,
+// or a connection that was refused, reset or closed before a response was
+// received.
+//
+// Requests are only retried while their context is active and when the
+// request body can be replayed. Other 4xx responses are never retried.
+//
+// A POST or PATCH without an Idempotency-Key header may already have been
+// processed when it fails, so it is only retried if it was refused before
+// reaching the API, rate limited or answered with a 503 and Retry-After.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first.
+	// A value of 1 or less disables retries.
+	MaxAttempts int
+
+	// BaseBackoff is the delay before the first retry, it doubles with each
+	// subsequent attempt.
+	BaseBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. If the API asks us to wait
+	// longer than this using Retry-After, the request is not retried.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction (0-1) of each delay that is randomised to avoid
+	// many clients retrying in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for most uses, making up
+// to three attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// RetryError wraps the final error of a request that was attempted more
+// than once. Use errors.As to retrieve the underlying *APIError.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+// Error returns the final error annotated with the number of attempts.
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v (after %d attempts)", e.Err, e.Attempts)
+}
+
+// Unwrap returns the final error.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// backoff returns the delay before the retry following attempt (starting at
+// 1). If res asks for a delay using Retry-After that is honored instead.
+// A negative delay means the request should not be retried.
+func (p RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	if wait, ok := retryAfter(res, time.Now()); ok {
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			return -1
+		}
+		return wait
+	}
+
+	delay := float64(p.BaseBackoff) * math.Pow(2, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// retryAfter parses the Retry-After header of res, which can either be a
+// number of seconds or an HTTP date.
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// idempotentMethods are the methods that can be safely repeated.
+var idempotentMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodOptions: true,
+	http.MethodPut: true, http.MethodDelete: true,
+}
+
+// retryableRequest reports whether req can be sent again after failing with
+// err. Requests that aren't idempotent are only retried when the API can't
+// have processed them, unless they have an Idempotency-Key.
+func retryableRequest(req *http.Request, res *http.Response, err error) bool {
+	if !retryable(err) {
+		return false
+	}
+	if idempotentMethods[req.Method] || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusServiceUnavailable {
+			_, ok := retryAfter(res, time.Now())
+			return ok
+		}
+		return apiErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryable reports whether err is a transient failure worth retrying.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
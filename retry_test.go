@@ -0,0 +1,225 @@
+package cloudmailin
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func retryClient(url string) Client {
+	return Client{
+		BaseURL:       url,
+		SMTPAccountID: "user",
+		SMTPToken:     "pass",
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+		},
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	t.Run("Retries until success with the same body", func(t *testing.T) {
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":"abc123"}`))
+		}))
+		defer server.Close()
+
+		message := buildMessage()
+		_, err := retryClient(server.URL).SendMail(&message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(bodies) != 3 || bodies[0] != bodies[2] || bodies[0] == "" {
+			t.Errorf("Expected the same body to be sent 3 times got {%q}", bodies)
+		}
+
+		if message.ID != "abc123" {
+			t.Errorf("Expected ID to be set got {%s}", message.ID)
+		}
+	})
+
+	t.Run("Reports attempts on the final error", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		message := buildMessage()
+		_, err := retryClient(server.URL).SendMail(&message)
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || retryErr.Attempts != 3 || attempts != 3 {
+			t.Fatalf("Expected 3 attempts got {%v}, %d", err, attempts)
+		}
+
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited got {%v}", err)
+		}
+	})
+
+	t.Run("Does not retry client errors", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}))
+		defer server.Close()
+
+		message := buildMessage()
+		_, err := retryClient(server.URL).SendMail(&message)
+
+		var retryErr *RetryError
+		if attempts != 1 || errors.As(err, &retryErr) || !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected a single attempt got {%v}, %d", err, attempts)
+		}
+	})
+
+	t.Run("Retries closed connections", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		message := buildMessage()
+		_, err := retryClient(server.URL).SendMail(&message)
+		if err != nil || attempts != 2 {
+			t.Errorf("Expected success on second attempt got {%v}, %d", err, attempts)
+		}
+	})
+
+	t.Run("Gives up when Retry-After exceeds MaxBackoff", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		message := buildMessage()
+		_, err := retryClient(server.URL).SendMail(&message)
+		if attempts != 1 || err == nil {
+			t.Errorf("Expected a single attempt got {%v}, %d", err, attempts)
+		}
+	})
+
+	t.Run("Does not retry a POST without an idempotency key", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := retryClient(server.URL)
+		client.AccountID, client.AccountToken = "account", "token"
+		_, err := client.Do("POST", "/addresses", nil, RequestTypeAccount)
+
+		var retryErr *RetryError
+		if attempts != 1 || err == nil || errors.As(err, &retryErr) {
+			t.Errorf("Expected a single attempt got {%v}, %d", err, attempts)
+		}
+	})
+
+	t.Run("Retries a POST without an idempotency key when rate limited", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := retryClient(server.URL)
+		client.AccountID, client.AccountToken = "account", "token"
+		_, err := client.Do("POST", "/addresses", nil, RequestTypeAccount)
+		if err != nil || attempts != 2 {
+			t.Errorf("Expected success on second attempt got {%v}, %d", err, attempts)
+		}
+	})
+
+	t.Run("No policy", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := retryClient(server.URL)
+		client.RetryPolicy = nil
+		message := buildMessage()
+		client.SendMail(&message)
+
+		if attempts != 1 {
+			t.Errorf("Expected a single attempt got %d", attempts)
+		}
+	})
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		header   string
+		expected time.Duration
+	}{
+		{"First", 1, "", time.Second},
+		{"Doubles", 2, "", 2 * time.Second},
+		{"Capped", 4, "", 5 * time.Second},
+		{"Retry-After seconds", 1, "3", 3 * time.Second},
+		{"Retry-After too long", 1, "60", -1},
+		{"Retry-After past date", 1, "Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"Retry-After invalid", 2, "soon", 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				res.Header.Set("Retry-After", tt.header)
+			}
+
+			if got := policy.backoff(tt.attempt, res); got != tt.expected {
+				t.Errorf("Expected %v got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Jitter", func(t *testing.T) {
+		jittered := RetryPolicy{BaseBackoff: time.Second, Jitter: 0.5}
+		got := jittered.backoff(1, nil)
+		if got < 500*time.Millisecond || got > time.Second {
+			t.Errorf("Expected jittered delay got %v", got)
+		}
+	})
+}
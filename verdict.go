@@ -0,0 +1,365 @@
+package cloudmailin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SPFResult is the result of an SPF check.
+type SPFResult string
+
+// The SPF results defined by RFC 7208.
+const (
+	SPFPass      SPFResult = "pass"
+	SPFFail      SPFResult = "fail"
+	SPFSoftFail  SPFResult = "softfail"
+	SPFNeutral   SPFResult = "neutral"
+	SPFNone      SPFResult = "none"
+	SPFTempError SPFResult = "temperror"
+	SPFPermError SPFResult = "permerror"
+)
+
+// Status returns the Result as an SPFResult. The result is converted to
+// lower case and an empty result is SPFNone.
+func (s IncomingMailEnvelopeSPF) Status() SPFResult {
+	result := SPFResult(strings.ToLower(strings.TrimSpace(s.Result)))
+	switch result {
+	case "":
+		return SPFNone
+	case "hardfail":
+		return SPFFail
+	}
+	return result
+}
+
+// DefaultSpamThreshold is the SpamAssassin score at or above which an email
+// is considered spam by Verdict.
+const DefaultSpamThreshold float32 = 5
+
+// IsSpam returns true if the email was scanned and the score is at or above
+// threshold.
+func (s IncomingMailEnvelopeSPAMD) IsSpam(threshold float32) bool {
+	return s.Success && s.Score >= threshold
+}
+
+// IsSpam returns true if the SpamAssassin score of the email is at or above
+// threshold, see IncomingMailEnvelopeSPAMD.IsSpam.
+func (m IncomingMail) IsSpam(threshold float32) bool {
+	return m.Envelope.SPAMD.IsSpam(threshold)
+}
+
+// AuthResult is the result of a check in an Authentication-Results header.
+type AuthResult string
+
+// The results defined by RFC 8601 for DKIM, DMARC and SPF.
+const (
+	AuthPass      AuthResult = "pass"
+	AuthFail      AuthResult = "fail"
+	AuthSoftFail  AuthResult = "softfail"
+	AuthNeutral   AuthResult = "neutral"
+	AuthNone      AuthResult = "none"
+	AuthPolicy    AuthResult = "policy"
+	AuthTempError AuthResult = "temperror"
+	AuthPermError AuthResult = "permerror"
+)
+
+// AuthenticationResult is a single check from an Authentication-Results
+// header such as dkim=pass header.d=example.com.
+type AuthenticationResult struct {
+	// Method is the check performed, such as dkim, spf or dmarc.
+	Method string
+	Result AuthResult
+
+	// Reason is the reason= value and Comment the first comment, such as
+	// (p=REJECT sp=REJECT dis=NONE) for DMARC.
+	Reason  string
+	Comment string
+
+	// Properties are the remaining values keyed by name, such as header.d
+	// or smtp.mailfrom.
+	Properties map[string]string
+}
+
+// AuthenticationResults is a parsed Authentication-Results header.
+type AuthenticationResults struct {
+	// AuthServID identifies the server that performed the checks.
+	AuthServID string
+	Results    []AuthenticationResult
+}
+
+var (
+	authCommentPattern = regexp.MustCompile(`\([^()]*\)`)
+	dmarcPolicyPattern = regexp.MustCompile(`(?i)\bp=(\w+)`)
+)
+
+// ParseAuthenticationResults parses the value of an Authentication-Results
+// header as defined by RFC 8601. Checks that can't be parsed are skipped.
+func ParseAuthenticationResults(value string) AuthenticationResults {
+	segments := splitUnquoted(value, ';')
+
+	results := AuthenticationResults{}
+	if len(segments) == 0 {
+		return results
+	}
+
+	// The authserv-id may be followed by a version.
+	if fields := strings.Fields(authCommentPattern.ReplaceAllString(segments[0], " ")); len(fields) > 0 {
+		results.AuthServID = fields[0]
+	}
+
+	for _, segment := range segments[1:] {
+		result := AuthenticationResult{Comment: firstComment(segment)}
+
+		// Comments may be nested so remove the innermost until none remain.
+		for authCommentPattern.MatchString(segment) {
+			segment = authCommentPattern.ReplaceAllString(segment, " ")
+		}
+
+		fields := splitUnquoted(segment, ' ', '\t', '\r', '\n')
+		if len(fields) == 0 {
+			continue
+		}
+
+		method, value, ok := splitProperty(fields[0])
+		if !ok {
+			continue
+		}
+		if slash := strings.Index(method, "/"); slash >= 0 {
+			method = method[:slash]
+		}
+		result.Method = strings.ToLower(method)
+		result.Result = AuthResult(strings.ToLower(value))
+
+		for _, field := range fields[1:] {
+			name, value, ok := splitProperty(field)
+			if !ok {
+				continue
+			}
+			if strings.EqualFold(name, "reason") {
+				result.Reason = value
+				continue
+			}
+			if result.Properties == nil {
+				result.Properties = make(map[string]string)
+			}
+			result.Properties[strings.ToLower(name)] = value
+		}
+
+		results.Results = append(results.Results, result)
+	}
+
+	return results
+}
+
+// splitUnquoted splits value at any of seps outside of double quotes and
+// comments, dropping empty parts.
+func splitUnquoted(value string, seps ...rune) []string {
+	var parts []string
+	var current strings.Builder
+	quoted, depth := false, 0
+
+	flush := func() {
+		if part := strings.TrimSpace(current.String()); part != "" {
+			parts = append(parts, part)
+		}
+		current.Reset()
+	}
+
+	for _, r := range value {
+		switch {
+		case r == '"' && depth == 0:
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+		case r == ')' && !quoted && depth > 0:
+			depth--
+		case !quoted && depth == 0 && containsRune(seps, r):
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return parts
+}
+
+// firstComment returns the content of the first comment in value.
+func firstComment(value string) string {
+	start, depth := -1, 0
+	for i, r := range value {
+		switch {
+		case r == '(':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(value[start:i])
+			}
+		}
+	}
+	return ""
+}
+
+func containsRune(runes []rune, r rune) bool {
+	for _, c := range runes {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
+
+// splitProperty splits name=value removing any quotes from the value.
+func splitProperty(field string) (name string, value string, ok bool) {
+	equals := strings.Index(field, "=")
+	if equals <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(field[:equals]),
+		strings.Trim(strings.TrimSpace(field[equals+1:]), `"`), true
+}
+
+// Find returns the checks performed using method, such as dkim.
+func (r AuthenticationResults) Find(method string) []AuthenticationResult {
+	var found []AuthenticationResult
+	for _, result := range r.Results {
+		if strings.EqualFold(result.Method, method) {
+			found = append(found, result)
+		}
+	}
+	return found
+}
+
+// AuthenticationResults parses each Authentication-Results header, the
+// first is the most recent.
+//
+// Senders can add their own Authentication-Results headers so only headers
+// added by servers you trust, identified by AuthServID, should be relied on.
+func (i IncomingMailHeaders) AuthenticationResults() []AuthenticationResults {
+	values := i.Find("authentication_results")
+	results := make([]AuthenticationResults, 0, len(values))
+	for _, value := range values {
+		results = append(results, ParseAuthenticationResults(value))
+	}
+	return results
+}
+
+// VerdictAction is the recommended action for an email, see Verdict.
+type VerdictAction string
+
+// The actions recommended by Verdict.
+const (
+	VerdictAccept     VerdictAction = "accept"
+	VerdictQuarantine VerdictAction = "quarantine"
+	VerdictReject     VerdictAction = "reject"
+)
+
+// Verdict summarizes the spam and authentication checks of an email.
+type Verdict struct {
+	Action VerdictAction
+
+	SPF   SPFResult
+	DKIM  AuthResult
+	DMARC AuthResult
+
+	Spam      bool
+	SpamScore float32
+
+	// Reasons explains why the email wasn't accepted.
+	Reasons []string
+}
+
+// Verdict summarizes the spam and authentication checks of the email so
+// that handlers can treat email consistently.
+//
+// SPF is taken from the envelope and DKIM and DMARC from the most recent
+// Authentication-Results header added by one of the trusted servers,
+// identified by their AuthServID. Senders can add their own headers so
+// headers from other servers are ignored, if none are trusted DKIM and DMARC
+// are AuthNone. The email is:
+//
+//   - rejected if DMARC fails and the domain's policy is reject.
+//   - quarantined if DMARC fails, if SPF fails without a DKIM or DMARC pass,
+//     or if the spam score is at or above DefaultSpamThreshold.
+//   - otherwise accepted.
+func (m IncomingMail) Verdict(trusted ...string) Verdict {
+	verdict := Verdict{
+		Action:    VerdictAccept,
+		SPF:       m.Envelope.SPF.Status(),
+		DKIM:      AuthNone,
+		DMARC:     AuthNone,
+		Spam:      m.IsSpam(DefaultSpamThreshold),
+		SpamScore: m.Envelope.SPAMD.Score,
+	}
+
+	var dmarcPolicy string
+	if results, ok := trustedResults(m.Headers.AuthenticationResults(), trusted); ok {
+		for _, dkim := range results.Find("dkim") {
+			if verdict.DKIM != AuthPass && verdict.DKIM != AuthFail {
+				verdict.DKIM = dkim.Result
+			}
+			if dkim.Result == AuthPass {
+				verdict.DKIM = AuthPass
+			}
+		}
+		if dmarc := results.Find("dmarc"); len(dmarc) > 0 {
+			verdict.DMARC = dmarc[0].Result
+			dmarcPolicy = dmarc[0].Properties["policy.published-domain-policy"]
+			if match := dmarcPolicyPattern.FindStringSubmatch(dmarc[0].Comment); dmarcPolicy == "" && match != nil {
+				dmarcPolicy = match[1]
+			}
+		}
+	}
+
+	if verdict.DMARC == AuthFail {
+		if strings.EqualFold(dmarcPolicy, "reject") {
+			verdict.escalate(VerdictReject, "DMARC failed with a reject policy")
+		} else {
+			verdict.escalate(VerdictQuarantine, "DMARC failed")
+		}
+	}
+
+	if verdict.SPF == SPFFail && verdict.DKIM != AuthPass && verdict.DMARC != AuthPass {
+		verdict.escalate(VerdictQuarantine, "SPF failed")
+	}
+
+	if verdict.Spam {
+		verdict.escalate(VerdictQuarantine, fmt.Sprintf("spam score %.1f", verdict.SpamScore))
+	}
+
+	return verdict
+}
+
+// trustedResults returns the first of results added by a server in trusted.
+func trustedResults(results []AuthenticationResults, trusted []string) (AuthenticationResults, bool) {
+	for _, result := range results {
+		for _, id := range trusted {
+			if strings.EqualFold(result.AuthServID, id) {
+				return result, true
+			}
+		}
+	}
+	return AuthenticationResults{}, false
+}
+
+// escalate records reason and raises the action to at least action.
+func (v *Verdict) escalate(action VerdictAction, reason string) {
+	v.Reasons = append(v.Reasons, reason)
+	if v.Action != VerdictReject {
+		v.Action = action
+	}
+}
+
+// Err returns an error rejecting the email if the Action is VerdictReject,
+// see RejectIncoming, otherwise nil.
+func (v Verdict) Err() error {
+	if v.Action != VerdictReject {
+		return nil
+	}
+	return RejectIncoming("rejected: " + strings.Join(v.Reasons, ", "))
+}
@@ -0,0 +1,229 @@
+package cloudmailin
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncomingMailEnvelopeSPF_Status(t *testing.T) {
+	tests := []struct {
+		result   string
+		expected SPFResult
+	}{
+		{"pass", SPFPass},
+		{"Fail", SPFFail},
+		{"hardfail", SPFFail},
+		{" SoftFail ", SPFSoftFail},
+		{"", SPFNone},
+		{"temperror", SPFTempError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.result, func(t *testing.T) {
+			if status := (IncomingMailEnvelopeSPF{Result: tt.result}).Status(); status != tt.expected {
+				t.Errorf("Expected {%v} got {%v}", tt.expected, status)
+			}
+		})
+	}
+}
+
+func TestIncomingMail_IsSpam(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	message, _ := ParseIncoming(data)
+
+	tests := []struct {
+		name      string
+		spamd     IncomingMailEnvelopeSPAMD
+		threshold float32
+		expected  bool
+	}{
+		{"Below", message.Envelope.SPAMD, 5, false},
+		{"Equal", message.Envelope.SPAMD, 2.5, true},
+		{"Above", message.Envelope.SPAMD, 1, true},
+		{"Not Scanned", IncomingMailEnvelopeSPAMD{Score: 10}, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message.Envelope.SPAMD = tt.spamd
+			if spam := message.IsSpam(tt.threshold); spam != tt.expected {
+				t.Errorf("Expected {%v} got {%v}", tt.expected, spam)
+			}
+		})
+	}
+}
+
+func TestParseAuthenticationResults(t *testing.T) {
+	value := "mx.google.com;\r\n       dkim=pass header.i=@example.com header.s=s1 header.b=\"Ab/c=\";\r\n" +
+		"       spf=pass (google.com: domain of a@example.com designates 192.0.2.1 as permitted sender)" +
+		" smtp.mailfrom=a@example.com;\r\n       dmarc=fail (p=REJECT sp=REJECT dis=REJECT) header.from=example.com;" +
+		" dkim/1=Neutral reason=\"bad; signature\" header.d=other.example"
+
+	expected := AuthenticationResults{
+		AuthServID: "mx.google.com",
+		Results: []AuthenticationResult{
+			{Method: "dkim", Result: AuthPass, Properties: map[string]string{
+				"header.i": "@example.com", "header.s": "s1", "header.b": "Ab/c=",
+			}},
+			{
+				Method: "spf", Result: AuthPass,
+				Comment:    "google.com: domain of a@example.com designates 192.0.2.1 as permitted sender",
+				Properties: map[string]string{"smtp.mailfrom": "a@example.com"},
+			},
+			{
+				Method: "dmarc", Result: AuthFail, Comment: "p=REJECT sp=REJECT dis=REJECT",
+				Properties: map[string]string{"header.from": "example.com"},
+			},
+			{
+				Method: "dkim", Result: AuthNeutral, Reason: "bad; signature",
+				Properties: map[string]string{"header.d": "other.example"},
+			},
+		},
+	}
+
+	results := ParseAuthenticationResults(value)
+	if !cmp.Equal(expected, results) {
+		t.Errorf("Expected {%v} got {%v}\n%v", expected, results, cmp.Diff(expected, results))
+	}
+
+	if dkim := results.Find("DKIM"); len(dkim) != 2 {
+		t.Errorf("Expected {2} got {%v}", len(dkim))
+	}
+
+	t.Run("None", func(t *testing.T) {
+		results := ParseAuthenticationResults("example.com 1; none")
+		expected := AuthenticationResults{AuthServID: "example.com"}
+		if !cmp.Equal(expected, results) {
+			t.Errorf("Expected {%v} got {%v}", expected, results)
+		}
+	})
+
+	t.Run("Nested Comment", func(t *testing.T) {
+		results := ParseAuthenticationResults("mx (a (b) c); spf=pass (x (y)) smtp.helo=z")
+		if len(results.Results) != 1 || results.AuthServID != "mx" ||
+			results.Results[0].Comment != "x (y)" || results.Results[0].Properties["smtp.helo"] != "z" {
+			t.Errorf("Unexpected result {%v}", results)
+		}
+	})
+}
+
+func TestIncomingMail_Verdict(t *testing.T) {
+	data, _ := os.Open("test/fixtures/post.json")
+	defer data.Close()
+	fixture, _ := ParseIncoming(data)
+
+	build := func(spf string, score float32, authResults ...string) IncomingMail {
+		message := fixture
+		message.Headers = IncomingMailHeaders{"authentication_results": authResults}
+		message.Envelope.SPF.Result = spf
+		message.Envelope.SPAMD.Score = score
+		return message
+	}
+
+	tests := []struct {
+		name     string
+		message  IncomingMail
+		expected Verdict
+	}{
+		{
+			"Fixture",
+			fixture,
+			Verdict{
+				Action: VerdictQuarantine, SPF: SPFFail, DKIM: AuthNone, DMARC: AuthNone,
+				SpamScore: 2.5, Reasons: []string{"SPF failed"},
+			},
+		},
+		{
+			"Pass",
+			build("pass", 1, "mx; dkim=pass; dmarc=pass"),
+			Verdict{Action: VerdictAccept, SPF: SPFPass, DKIM: AuthPass, DMARC: AuthPass, SpamScore: 1},
+		},
+		{
+			"SPF Fail With DKIM Pass",
+			build("fail", 1, "mx; dkim=fail; dkim=pass"),
+			Verdict{Action: VerdictAccept, SPF: SPFFail, DKIM: AuthPass, DMARC: AuthNone, SpamScore: 1},
+		},
+		{
+			"DMARC Fail",
+			build("pass", 1, "mx; dkim=fail; dmarc=fail (p=none)"),
+			Verdict{
+				Action: VerdictQuarantine, SPF: SPFPass, DKIM: AuthFail, DMARC: AuthFail, SpamScore: 1,
+				Reasons: []string{"DMARC failed"},
+			},
+		},
+		{
+			"DMARC Reject",
+			build("fail", 7, "mx; dmarc=fail (p=REJECT sp=NONE)", "older; dmarc=pass"),
+			Verdict{
+				Action: VerdictReject, SPF: SPFFail, DKIM: AuthNone, DMARC: AuthFail, Spam: true, SpamScore: 7,
+				Reasons: []string{"DMARC failed with a reject policy", "SPF failed", "spam score 7.0"},
+			},
+		},
+		{
+			"DMARC Policy Property",
+			build("", 1, "mx; dmarc=fail policy.published-domain-policy=reject"),
+			Verdict{
+				Action: VerdictReject, SPF: SPFNone, DKIM: AuthNone, DMARC: AuthFail, SpamScore: 1,
+				Reasons: []string{"DMARC failed with a reject policy"},
+			},
+		},
+		{
+			"Forged Header",
+			build("fail", 1, "evil.example; dkim=pass; dmarc=pass"),
+			Verdict{
+				Action: VerdictQuarantine, SPF: SPFFail, DKIM: AuthNone, DMARC: AuthNone, SpamScore: 1,
+				Reasons: []string{"SPF failed"},
+			},
+		},
+		{
+			"Trusted Below Forged",
+			build("pass", 1, "evil.example; dmarc=pass", "MX.example.com; dmarc=fail (p=reject)"),
+			Verdict{
+				Action: VerdictReject, SPF: SPFPass, DKIM: AuthNone, DMARC: AuthFail, SpamScore: 1,
+				Reasons: []string{"DMARC failed with a reject policy"},
+			},
+		},
+		{
+			"Spam",
+			build("pass", 5, "mx; dkim=pass"),
+			Verdict{
+				Action: VerdictQuarantine, SPF: SPFPass, DKIM: AuthPass, DMARC: AuthNone, Spam: true,
+				SpamScore: 5, Reasons: []string{"spam score 5.0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := tt.message.Verdict("mx", "mx.example.com")
+			if !cmp.Equal(tt.expected, verdict) {
+				t.Errorf("Expected {%v} got {%v}\n%v", tt.expected, verdict, cmp.Diff(tt.expected, verdict))
+			}
+		})
+	}
+
+	t.Run("No Trusted Servers", func(t *testing.T) {
+		verdict := build("fail", 1, "mx; dkim=pass; dmarc=pass").Verdict()
+		if verdict.Action != VerdictQuarantine || verdict.DKIM != AuthNone || verdict.DMARC != AuthNone {
+			t.Errorf("Expected authentication results to be ignored got {%v}", verdict)
+		}
+	})
+}
+
+func TestVerdict_Err(t *testing.T) {
+	if err := (Verdict{Action: VerdictQuarantine}).Err(); err != nil {
+		t.Errorf("Expected nil got {%v}", err)
+	}
+
+	err := Verdict{Action: VerdictReject, Reasons: []string{"DMARC failed with a reject policy"}}.Err()
+	var incomingErr *IncomingError
+	if !errors.As(err, &incomingErr) || incomingErr.StatusCode != http.StatusForbidden ||
+		incomingErr.Message != "rejected: DMARC failed with a reject policy" {
+		t.Errorf("Expected rejection got {%v}", err)
+	}
+}